@@ -8,6 +8,7 @@ require (
 	github.com/cenkalti/backoff/v4 v4.3.0
 	github.com/coder/websocket v1.8.13
 	github.com/diskfs/go-diskfs v1.4.2
+	github.com/eclipse/paho.mqtt.golang v1.4.3
 	github.com/flosch/pongo2/v6 v6.0.0
 	github.com/go-chi/chi/v5 v5.1.0
 	github.com/google/uuid v1.6.0
@@ -19,6 +20,8 @@ require (
 	github.com/the-maldridge/authware v0.1.6-0.20250811011214-ba553bf067fc
 	github.com/vishvananda/netlink v1.3.0
 	go.bug.st/serial v1.6.2
+	golang.org/x/crypto v0.37.0
+	rsc.io/qr v0.2.0
 )
 
 require (
@@ -29,6 +32,7 @@ require (
 	github.com/djherbis/times v1.6.0 // indirect
 	github.com/elliotwutingfeng/asciiset v0.0.0-20230602022725-51bbb787efab // indirect
 	github.com/fatih/color v1.17.0 // indirect
+	github.com/gorilla/websocket v1.5.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/kballard/go-shellquote v0.0.0-20180428030007-95032a82bc51 // indirect
 	github.com/klauspost/compress v1.17.9 // indirect
@@ -48,10 +52,10 @@ require (
 	github.com/tg123/go-htpasswd v1.2.4 // indirect
 	github.com/ulikunitz/xz v0.5.11 // indirect
 	github.com/vishvananda/netns v0.0.4 // indirect
-	golang.org/x/crypto v0.37.0 // indirect
+	golang.org/x/net v0.27.0 // indirect
+	golang.org/x/sync v0.13.0 // indirect
 	golang.org/x/sys v0.32.0 // indirect
 	golang.org/x/term v0.31.0 // indirect
 	golang.org/x/text v0.24.0 // indirect
 	google.golang.org/protobuf v1.36.6 // indirect
-	rsc.io/qr v0.2.0 // indirect
 )
@@ -11,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/vishvananda/netlink"
 
+	"github.com/gizmo-platform/gizmo/pkg/ds"
 	"github.com/gizmo-platform/gizmo/pkg/sysconf"
 )
 
@@ -53,6 +54,9 @@ func dsLinkMonitorCmdRun(c *cobra.Command, args []string) {
 				appLogger.Info("Operational state change", "state", l.Attrs().OperState)
 				switch l.Attrs().OperState {
 				case netlink.OperDown:
+					if err := ds.RecordLinkFlap(); err != nil {
+						appLogger.Warn("Could not record link flap", "error", err)
+					}
 					r.Restart("gizmo-ds")
 				}
 			}
@@ -0,0 +1,118 @@
+//go:build linux
+
+package cmdlets
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/gizmo-platform/gizmo/pkg/fms"
+	"github.com/gizmo-platform/gizmo/pkg/match"
+)
+
+var (
+	fmsExportTelemetryCmd = &cobra.Command{
+		Use:   "export-telemetry",
+		Short: "export-telemetry writes a team's telemetry for a match as CSV",
+		Long:  fmsExportTelemetryCmdLongDocs,
+		Run:   fmsExportTelemetryCmdRun,
+	}
+
+	fmsExportTelemetryCmdLongDocs = `export-telemetry reads the telemetry that the FMS saved in the match
+archive and writes it out as a CSV file.  Matches are selected by
+their match number, and if a match was remapped while it was running
+the telemetry from every record for that match is combined.  A
+specific record can be selected instead with --record.`
+)
+
+func init() {
+	fmsCmd.AddCommand(fmsExportTelemetryCmd)
+	fmsExportTelemetryCmd.Flags().Int("team", 0, "Team to export telemetry for")
+	fmsExportTelemetryCmd.Flags().Int("match", 0, "Match number to export")
+	fmsExportTelemetryCmd.Flags().Int("record", 0, "Record ID to export instead of a match number")
+	fmsExportTelemetryCmd.Flags().String("archive", "/var/lib/gizmo/matches", "Match archive directory")
+	fmsExportTelemetryCmd.Flags().String("out", "", "File to write to instead of stdout")
+}
+
+func fmsExportTelemetryCmdRun(c *cobra.Command, args []string) {
+	initLogger("export-telemetry")
+
+	team, _ := c.Flags().GetInt("team")
+	number, _ := c.Flags().GetInt("match")
+	recordID, _ := c.Flags().GetInt("record")
+	dir, _ := c.Flags().GetString("archive")
+	outFile, _ := c.Flags().GetString("out")
+
+	if team == 0 || (number == 0 && recordID == 0) {
+		fmt.Fprintln(os.Stderr, "A team and either a match or a record must be specified")
+		os.Exit(1)
+	}
+
+	archive := match.New(match.WithLogger(appLogger), match.WithDirectory(dir))
+	records, err := archive.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading match archive: %s\n", err)
+		os.Exit(2)
+	}
+
+	ids := []int{}
+	for _, r := range records {
+		if (recordID != 0 && r.ID == recordID) || (recordID == 0 && r.Number == number) {
+			ids = append(ids, r.ID)
+		}
+	}
+	if len(ids) == 0 {
+		fmt.Fprintln(os.Stderr, "No matching records were found")
+		os.Exit(1)
+	}
+
+	var out io.Writer = os.Stdout
+	if outFile != "" {
+		f, err := os.Create(outFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %s\n", err)
+			os.Exit(2)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := csv.NewWriter(out)
+	wroteHeader := false
+	for _, id := range ids {
+		src, err := archive.OpenArtifact(id, fms.TelemetryCSVName(team))
+		if errors.Is(err, match.ErrNoSuchArtifact) {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening telemetry for record %d: %s\n", id, err)
+			os.Exit(2)
+		}
+
+		rows, err := csv.NewReader(src).ReadAll()
+		src.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading telemetry for record %d: %s\n", id, err)
+			os.Exit(2)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		if wroteHeader {
+			rows = rows[1:]
+		}
+		w.WriteAll(rows)
+		wroteHeader = true
+	}
+	w.Flush()
+
+	if !wroteHeader {
+		fmt.Fprintf(os.Stderr, "No telemetry was recorded for team %d\n", team)
+		os.Exit(1)
+	}
+}
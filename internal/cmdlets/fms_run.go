@@ -11,13 +11,20 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/spf13/cobra"
 
+	"github.com/gizmo-platform/gizmo/pkg/bracket"
 	"github.com/gizmo-platform/gizmo/pkg/config"
 	"github.com/gizmo-platform/gizmo/pkg/eventstream"
 	"github.com/gizmo-platform/gizmo/pkg/fms"
+	"github.com/gizmo-platform/gizmo/pkg/inspection"
+	"github.com/gizmo-platform/gizmo/pkg/match"
+	"github.com/gizmo-platform/gizmo/pkg/practice"
 	rconfig "github.com/gizmo-platform/gizmo/pkg/routeros/config"
 	"github.com/gizmo-platform/gizmo/pkg/routeros/netinstall"
+	"github.com/gizmo-platform/gizmo/pkg/schedule"
 	"github.com/gizmo-platform/gizmo/pkg/tlm/net"
 )
 
@@ -50,7 +57,14 @@ func fmsRunCmdRun(c *cobra.Command, args []string) {
 		return
 	}
 
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+
 	es := eventstream.New(appLogger)
+	reg.MustRegister(es)
 	appLogger.Debug("EventStream Init")
 
 	routerAddr := "100.64.0.1"
@@ -59,6 +73,7 @@ func fmsRunCmdRun(c *cobra.Command, args []string) {
 		rconfig.WithLogger(appLogger),
 		rconfig.WithRouter(routerAddr),
 		rconfig.WithEventStreamer(es),
+		rconfig.WithRegisterer(reg),
 	}
 	if os.Getenv("GIZMO_FMS_STATEDIR") != "" {
 		opts = append(opts, rconfig.WithStateDirectory(os.Getenv("GIZMO_FMS_STATEDIR")))
@@ -76,6 +91,51 @@ func fmsRunCmdRun(c *cobra.Command, args []string) {
 	}
 	appLogger.Debug("TLM Init")
 
+	archive := match.New(
+		match.WithLogger(appLogger),
+		match.WithDirectory("matches"),
+	)
+	if err := archive.Recover(); err != nil {
+		appLogger.Warn("Could not recover match archive", "error", err)
+	}
+	appLogger.Debug("Archive Init")
+
+	quals := schedule.New(
+		schedule.WithLogger(appLogger),
+		schedule.WithFile("schedule.json"),
+	)
+	if err := quals.Load(); err != nil {
+		appLogger.Warn("Could not load qualification schedule", "error", err)
+	}
+	appLogger.Debug("Schedule Init")
+
+	elims := bracket.New(
+		bracket.WithLogger(appLogger),
+		bracket.WithFile("bracket.json"),
+	)
+	if err := elims.Load(); err != nil {
+		appLogger.Warn("Could not load elimination bracket", "error", err)
+	}
+	appLogger.Debug("Bracket Init")
+
+	bookings := practice.New(
+		practice.WithLogger(appLogger),
+		practice.WithFile("practice.json"),
+	)
+	if err := bookings.Load(); err != nil {
+		appLogger.Warn("Could not load practice bookings", "error", err)
+	}
+	appLogger.Debug("Practice Init")
+
+	inspections := inspection.New(
+		inspection.WithLogger(appLogger),
+		inspection.WithFile("inspection.json"),
+	)
+	if err := inspections.Load(); err != nil {
+		appLogger.Warn("Could not load inspections", "error", err)
+	}
+	appLogger.Debug("Inspection Init")
+
 	nsf := netinstall.NewFetcher(
 		netinstall.WithFetcherLogger(appLogger),
 		netinstall.WithFetcherEventStreamer(es),
@@ -91,6 +151,12 @@ func fmsRunCmdRun(c *cobra.Command, args []string) {
 		fms.WithEventStreamer(es),
 		fms.WithFileFetcher(nsf),
 		fms.WithNetController(controller),
+		fms.WithMatchArchive(archive),
+		fms.WithScheduleStore(quals),
+		fms.WithBracketStore(elims),
+		fms.WithPracticeStore(bookings),
+		fms.WithInspectionStore(inspections),
+		fms.WithPrometheusRegistry(reg),
 	)
 	appLogger.Debug("HTTP Init")
 
@@ -0,0 +1,216 @@
+// Package bracket builds and tracks single elimination brackets for
+// alliance eliminations.
+package bracket
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrTooFewAlliances is returned when a bracket is requested
+	// for fewer than two alliances.
+	ErrTooFewAlliances = errors.New("at least two alliances are required")
+
+	// ErrNoSuchMatch is returned when a match is requested that
+	// isn't in the bracket.
+	ErrNoSuchMatch = errors.New("no bracket match with that ID exists")
+
+	// ErrNotReady is returned when a result is entered for a match
+	// that doesn't have both of its alliances yet.
+	ErrNotReady = errors.New("both alliances for the match are not known yet")
+
+	// ErrTie is returned when a result is entered that doesn't have
+	// a winner.  Tied matches must be replayed.
+	ErrTie = errors.New("eliminations matches cannot end in a tie")
+
+	// ErrAlreadyAdvanced is returned when a result is changed after
+	// the winner has already played in a later match.
+	ErrAlreadyAdvanced = errors.New("the winner has already played in a later round")
+)
+
+// Generate builds a bracket for the alliances, which must be listed
+// in seed order.  If the number of alliances isn't a power of two the
+// top seeds get a bye through the first round.
+func Generate(field int, alliances []Alliance) (Bracket, error) {
+	if len(alliances) < 2 {
+		return Bracket{}, ErrTooFewAlliances
+	}
+
+	b := Bracket{
+		Created:   time.Now(),
+		Field:     field,
+		Alliances: make([]Alliance, len(alliances)),
+	}
+	for i, a := range alliances {
+		a.Seed = i + 1
+		b.Alliances[i] = a
+	}
+
+	size := 2
+	rounds := 1
+	for size < len(alliances) {
+		size *= 2
+		rounds++
+	}
+
+	// Each slot is either an alliance that has been seeded into
+	// the round, or the winner of a match in the round before.
+	type slot struct{ seed, from int }
+	slots := []slot{}
+	order := seeding(size)
+	for i := 0; i < len(order); i += 2 {
+		if order[i+1] > len(alliances) {
+			slots = append(slots, slot{seed: order[i]})
+			continue
+		}
+		slots = append(slots, slot{from: b.add(1, [2]int{order[i], order[i+1]}, [2]int{})})
+	}
+
+	for round := 2; round <= rounds; round++ {
+		next := []slot{}
+		for i := 0; i < len(slots); i += 2 {
+			a, c := slots[i], slots[i+1]
+			next = append(next, slot{from: b.add(round, [2]int{a.seed, c.seed}, [2]int{a.from, c.from})})
+		}
+		slots = next
+	}
+
+	b.name()
+	return b, nil
+}
+
+// seeding returns the order that seeds are paired in so that the top
+// seeds meet as late as possible, for example 1, 8, 4, 5, 2, 7, 3, 6.
+func seeding(size int) []int {
+	order := []int{1}
+	for n := 2; n <= size; n *= 2 {
+		next := make([]int, 0, n)
+		for _, s := range order {
+			next = append(next, s, n+1-s)
+		}
+		order = next
+	}
+	return order
+}
+
+func (b *Bracket) add(round int, alliances, from [2]int) int {
+	id := len(b.Matches) + 1
+	b.Matches = append(b.Matches, Match{
+		ID:        id,
+		Round:     round,
+		Alliances: alliances,
+		From:      from,
+	})
+	return id
+}
+
+// name labels each match by how far it is from the final.
+func (b *Bracket) name() {
+	rounds := 0
+	perRound := make(map[int]int)
+	for _, m := range b.Matches {
+		perRound[m.Round]++
+		if m.Round > rounds {
+			rounds = m.Round
+		}
+	}
+
+	seen := make(map[int]int)
+	for i := range b.Matches {
+		m := &b.Matches[i]
+		seen[m.Round]++
+
+		name := ""
+		switch rounds - m.Round {
+		case 0:
+			name = "Final"
+		case 1:
+			name = "Semifinal"
+		case 2:
+			name = "Quarterfinal"
+		default:
+			name = fmt.Sprintf("Round of %d", 2<<(rounds-m.Round))
+		}
+		if perRound[m.Round] > 1 {
+			name = fmt.Sprintf("%s %d", name, seen[m.Round])
+		}
+		m.Name = name
+	}
+}
+
+// Match returns the match with the given ID.
+func (b Bracket) Match(id int) (Match, error) {
+	if id < 1 || id > len(b.Matches) {
+		return Match{}, ErrNoSuchMatch
+	}
+	return b.Matches[id-1], nil
+}
+
+// Alliance returns the alliance with the given seed.
+func (b Bracket) Alliance(seed int) (Alliance, bool) {
+	if seed < 1 || seed > len(b.Alliances) {
+		return Alliance{}, false
+	}
+	return b.Alliances[seed-1], true
+}
+
+// Next returns the first match that is ready to be played.
+func (b Bracket) Next() (Match, bool) {
+	for _, m := range b.Matches {
+		if m.Ready() {
+			return m, true
+		}
+	}
+	return Match{}, false
+}
+
+// Champion returns the seed of the alliance that won the final, or
+// zero if it hasn't been played yet.
+func (b Bracket) Champion() int {
+	if len(b.Matches) == 0 {
+		return 0
+	}
+	return b.Matches[len(b.Matches)-1].Winner
+}
+
+// Record enters the result of a match and advances the winner.  A
+// result may be corrected until the winner has played their next
+// match.
+func (b *Bracket) Record(id int, scores [2]int) error {
+	m, err := b.Match(id)
+	if err != nil {
+		return err
+	}
+	if m.Alliances[0] == 0 || m.Alliances[1] == 0 {
+		return ErrNotReady
+	}
+	if scores[0] == scores[1] {
+		return ErrTie
+	}
+
+	winner := m.Alliances[0]
+	if scores[1] > scores[0] {
+		winner = m.Alliances[1]
+	}
+
+	for i := range b.Matches {
+		if (b.Matches[i].From[0] == id || b.Matches[i].From[1] == id) && b.Matches[i].Winner != 0 {
+			return ErrAlreadyAdvanced
+		}
+	}
+	for i := range b.Matches {
+		for slot, from := range b.Matches[i].From {
+			if from == id {
+				b.Matches[i].Alliances[slot] = winner
+			}
+		}
+	}
+
+	m.Scores = scores
+	m.Winner = winner
+	m.Played = time.Now()
+	b.Matches[id-1] = m
+	return nil
+}
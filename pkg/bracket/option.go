@@ -0,0 +1,19 @@
+package bracket
+
+import (
+	"github.com/hashicorp/go-hclog"
+)
+
+// WithLogger configures the logger for the store.
+func WithLogger(l hclog.Logger) Option {
+	return func(s *Store) {
+		s.l = l.Named("bracket")
+	}
+}
+
+// WithFile sets the file that the bracket is saved to.
+func WithFile(p string) Option {
+	return func(s *Store) {
+		s.path = p
+	}
+}
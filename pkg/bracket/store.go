@@ -0,0 +1,96 @@
+package bracket
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/gizmo-platform/gizmo/pkg/util"
+)
+
+// New returns a store configured with the given options.
+func New(opts ...Option) *Store {
+	s := new(Store)
+	s.l = hclog.NewNullLogger()
+	s.path = "bracket.json"
+
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Load reads the bracket back from disk.  It is not an error for
+// there to be no bracket yet.
+func (s *Store) Load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	b := Bracket{}
+	if err := json.NewDecoder(f).Decode(&b); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.current = b
+	s.mutex.Unlock()
+	s.l.Info("Bracket loaded", "alliances", len(b.Alliances))
+	return nil
+}
+
+// Get returns a copy of the current bracket.
+func (s *Store) Get() Bracket {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.current.copy()
+}
+
+// Replace swaps the current bracket for a new one and saves it.
+func (s *Store) Replace(b Bracket) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.save(b); err != nil {
+		return err
+	}
+	s.current = b.copy()
+	s.l.Info("Bracket replaced", "alliances", len(b.Alliances), "matches", len(b.Matches))
+	return nil
+}
+
+// Record enters the result of a match and saves the bracket.  The
+// updated bracket is returned.
+func (s *Store) Record(id int, scores [2]int) (Bracket, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	b := s.current.copy()
+	if err := b.Record(id, scores); err != nil {
+		return Bracket{}, err
+	}
+	if err := s.save(b); err != nil {
+		return Bracket{}, err
+	}
+	s.current = b
+	m, _ := b.Match(id)
+	s.l.Info("Bracket result recorded", "match", m.Name, "winner", m.Winner)
+	return b.copy(), nil
+}
+
+func (s *Store) save(b Bracket) error {
+	return util.WriteJSONFile(s.path, b)
+}
+
+func (b Bracket) copy() Bracket {
+	b.Alliances = append([]Alliance{}, b.Alliances...)
+	b.Matches = append([]Match{}, b.Matches...)
+	return b
+}
@@ -0,0 +1,65 @@
+package bracket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Store keeps the elimination bracket so that it survives restarts
+// of the FMS.
+type Store struct {
+	l hclog.Logger
+
+	path string
+
+	mutex   sync.RWMutex
+	current Bracket
+}
+
+// Option configures the Store.
+type Option func(*Store)
+
+// Bracket is a single elimination bracket between alliances.  All
+// matches are played on one field.
+type Bracket struct {
+	Created   time.Time
+	Field     int
+	Alliances []Alliance
+	Matches   []Match
+}
+
+// Alliance is a group of teams that play together through the
+// eliminations.  Alliances are identified by their seed, which starts
+// at 1.
+type Alliance struct {
+	Seed  int
+	Teams []int
+}
+
+// Match is a single match of the bracket.  Matches are listed in the
+// order they are to be played, and each one is between the alliances
+// in its two slots.  A slot is filled either when the bracket is
+// generated or when the match it comes from has a winner.
+type Match struct {
+	ID    int
+	Round int
+	Name  string
+
+	// Alliances holds the seed of the alliance in each slot, or
+	// zero if it isn't known yet.  From holds the ID of the match
+	// whose winner fills the slot, or zero if it was seeded.
+	Alliances [2]int
+	From      [2]int
+
+	Scores [2]int
+	Winner int
+	Played time.Time
+}
+
+// Ready returns true if both alliances are known and the match hasn't
+// been played.
+func (m Match) Ready() bool {
+	return m.Alliances[0] != 0 && m.Alliances[1] != 0 && m.Winner == 0
+}
@@ -120,7 +120,8 @@ func (c *FMSConfig) populateRequiredElements() bool {
 		(c.AdminPass == "") || (c.AutoPass == "") || (c.ViewPass == "") ||
 		(c.InfrastructureSSID == "") || (c.RadioMode == "") ||
 		(c.CompatHardwareVersions == "") || (c.CompatFirmwareVersions == "") ||
-		(c.CompatDSBootmodes == "") || (c.CompatDSVersions == "")
+		(c.CompatDSBootmodes == "") || (c.CompatDSVersions == "") ||
+		(c.AlertBatteryVoltage == nil) || (c.AlertRSSI == nil) || (c.AlertControlFrameAge == nil)
 
 	xkcd := xkcdpwgen.NewGenerator()
 	xkcd.SetNumWords(3)
@@ -180,5 +181,19 @@ func (c *FMSConfig) populateRequiredElements() bool {
 		c.CompatDSVersions = buildinfo.Version // Always accept own version
 	}
 
+	// Set defaults for the thresholds that drive alerts.
+	if c.AlertBatteryVoltage == nil {
+		vbat := 6.8
+		c.AlertBatteryVoltage = &vbat
+	}
+	if c.AlertRSSI == nil {
+		rssi := -75
+		c.AlertRSSI = &rssi
+	}
+	if c.AlertControlFrameAge == nil {
+		age := 0.5
+		c.AlertControlFrameAge = &age
+	}
+
 	return needSave
 }
@@ -1,27 +1,103 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Integrations register themselves so that the rest of the system
+// can find out what they are called and which settings they take
+// without knowing about each one.  The ID of an integration is
+// stored in the config, so it must never change once assigned.
+
+const (
+	// SettingText is a free-form text setting.
+	SettingText = "text"
+
+	// SettingPassword is a text setting that shouldn't be shown.
+	SettingPassword = "password"
+
+	// SettingBool is an on or off setting.
+	SettingBool = "bool"
+)
+
 var (
-	allIntegrations  = IntegrationSlice{IntegrationPCSM}
-	integrationNames = map[Integration]string{
-		IntegrationPCSM: "BEST Robotics PCSM",
+	// ErrUnknownIntegration is returned when settings are provided
+	// for an integration that hasn't been registered.
+	ErrUnknownIntegration = errors.New("no integration with that ID exists")
+
+	registry = make(map[Integration]IntegrationInfo)
+)
+
+// IntegrationSetting describes a single setting that an integration
+// takes.  All settings are stored as strings.
+type IntegrationSetting struct {
+	Key      string
+	Label    string
+	Type     string
+	Default  string
+	Required bool
+}
+
+// IntegrationInfo describes an integration.  The slug is used in
+// URLs, and the name is what is shown to people.
+type IntegrationInfo struct {
+	ID          Integration
+	Slug        string
+	Name        string
+	Description string
+	Settings    []IntegrationSetting
+}
+
+// RegisterIntegration makes an integration known.  It is meant to be
+// called from init(), and panics if the ID or slug is already taken
+// since that is always a programming error.
+func RegisterIntegration(info IntegrationInfo) {
+	for _, existing := range registry {
+		if existing.ID == info.ID || existing.Slug == info.Slug {
+			panic(fmt.Sprintf("integration %d (%s) is already registered", info.ID, info.Slug))
+		}
+	}
+	registry[info.ID] = info
+}
+
+// RegisteredIntegrations returns every known integration in order of
+// ID.
+func RegisteredIntegrations() []IntegrationInfo {
+	out := make([]IntegrationInfo, 0, len(registry))
+	for _, info := range registry {
+		out = append(out, info)
 	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Info returns the registered information for the integration.
+func (i Integration) Info() (IntegrationInfo, bool) {
+	info, ok := registry[i]
+	return info, ok
+}
 
-	integrationKeys = func(m map[Integration]string) map[string]Integration {
-		out := make(map[string]Integration, len(m))
-		for k, v := range m {
-			out[v] = k
+// Setting returns the setting with the given key.
+func (info IntegrationInfo) Setting(key string) (IntegrationSetting, bool) {
+	for _, s := range info.Settings {
+		if s.Key == key {
+			return s, true
 		}
-		return out
-	}(integrationNames)
-)
+	}
+	return IntegrationSetting{}, false
+}
 
 // ToStrings converts integrations into a slice of strings.
 func (is IntegrationSlice) ToStrings() []string {
 	out := make([]string, len(is))
 	for idx, integration := range is {
-		switch integration {
-		case IntegrationPCSM:
-			out[idx] = integrationNames[integration]
+		if info, ok := integration.Info(); ok {
+			out[idx] = info.Name
 		}
 	}
 	return out
@@ -43,12 +119,83 @@ func IntegrationsFromStrings(s []string) IntegrationSlice {
 	out := IntegrationSlice{}
 
 	for _, name := range s {
-		i, ok := integrationKeys[name]
-		if !ok {
-			continue
+		for _, info := range RegisteredIntegrations() {
+			if info.Name == name {
+				out = append(out, info.ID)
+			}
 		}
-		out = append(out, i)
 	}
 
 	return out
 }
+
+func allIntegrations() IntegrationSlice {
+	out := IntegrationSlice{}
+	for _, info := range RegisteredIntegrations() {
+		out = append(out, info.ID)
+	}
+	return out
+}
+
+// IntegrationSetting returns the value of a setting for an
+// integration, or the default if it hasn't been set.
+func (c *FMSConfig) IntegrationSetting(i Integration, key string) string {
+	if v, ok := c.IntegrationSettings[i][key]; ok {
+		return v
+	}
+	info, _ := i.Info()
+	s, _ := info.Setting(key)
+	return s.Default
+}
+
+// IntegrationSettingBool returns the value of an on or off setting.
+func (c *FMSConfig) IntegrationSettingBool(i Integration, key string) bool {
+	b, _ := strconv.ParseBool(c.IntegrationSetting(i, key))
+	return b
+}
+
+// SetIntegrationSettings checks the settings against what the
+// integration takes, and replaces the existing settings if they are
+// acceptable.  Passwords aren't shown once they are set, so a password
+// that is left empty keeps its existing value.
+func (c *FMSConfig) SetIntegrationSettings(i Integration, settings map[string]string) error {
+	info, ok := i.Info()
+	if !ok {
+		return ErrUnknownIntegration
+	}
+	if settings == nil {
+		settings = make(map[string]string)
+	}
+
+	for _, s := range info.Settings {
+		if s.Type != SettingPassword || settings[s.Key] != "" {
+			continue
+		}
+		if old, ok := c.IntegrationSettings[i][s.Key]; ok {
+			settings[s.Key] = old
+		}
+	}
+
+	for key, value := range settings {
+		s, ok := info.Setting(key)
+		if !ok {
+			return fmt.Errorf("%s does not have a setting named %s", info.Name, key)
+		}
+		if s.Type == SettingBool {
+			if _, err := strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("%s must be true or false", s.Label)
+			}
+		}
+	}
+	for _, s := range info.Settings {
+		if s.Required && settings[s.Key] == "" {
+			return fmt.Errorf("%s must be set", s.Label)
+		}
+	}
+
+	if c.IntegrationSettings == nil {
+		c.IntegrationSettings = make(map[Integration]map[string]string)
+	}
+	c.IntegrationSettings[i] = settings
+	return nil
+}
@@ -16,6 +16,11 @@ type GSSConfig struct {
 	NetPSK   string
 	ServerIP string
 	FieldIP  string
+
+	// MetricsTTL is how many seconds the driver's station waits
+	// without a report from the Gizmo before treating it as
+	// offline.  Zero uses the default.
+	MetricsTTL int
 }
 
 // DSMeta stores information reported by the Driver's Station metadata
@@ -23,6 +28,19 @@ type GSSConfig struct {
 type DSMeta struct {
 	Version  string
 	Bootmode string
+
+	Health DSHealth
+}
+
+// DSHealth is the driver's station's view of its own health, which
+// helps tell driver's station problems apart from robot problems.
+type DSHealth struct {
+	GamepadBound bool
+	RadioMode    string
+	RadioChannel string
+	Stations     int
+	GizmoSignal  int
+	LinkFlaps    int
 }
 
 // VersionOK compares the version against a string containing all
@@ -43,6 +61,24 @@ func (d DSMeta) BootmodeOK(bList string) bool {
 	return strings.Contains(bList, d.Bootmode)
 }
 
+// Problems describes anything wrong with the driver's station
+// itself.  Driver's stations that predate health reporting never have
+// any problems.
+func (h DSHealth) Problems() []string {
+	if h.RadioMode == "" {
+		return nil
+	}
+
+	out := []string{}
+	if !h.GamepadBound {
+		out = append(out, "No gamepad")
+	}
+	if h.RadioMode == "DS" && h.Stations == 0 {
+		out = append(out, "Gizmo not associated")
+	}
+	return out
+}
+
 // GizmoMeta stores information reported by the Gizmo metadata feed.
 type GizmoMeta struct {
 	HardwareVersion string
@@ -74,6 +110,53 @@ type Field struct {
 	MAC string
 
 	Channel string
+
+	// AutoMap fields map teams to whichever quad their driver's
+	// station is plugged into, rather than requiring a volunteer
+	// to maintain the map.  This is intended for practice fields.
+	AutoMap bool
+}
+
+// Webhook is an HTTP endpoint that is notified of field events.  If
+// a secret is set, each request is signed with it so that the
+// receiver can verify it came from the FMS.  A webhook with no events
+// listed receives all of them.
+type Webhook struct {
+	ID     string
+	URL    string
+	Secret string
+	Events []string
+}
+
+// Wants returns true if the webhook should receive the named event.
+func (w *Webhook) Wants(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ScoringElement is something that earns points during a match.
+// Penalties are elements that are worth negative points.  The key is
+// what scores are stored against, so it should not be changed once
+// scores have been entered.
+type ScoringElement struct {
+	Key    string
+	Name   string
+	Points int
+}
+
+// InspectionItem is something an inspector checks on every robot.
+// The key is what results are stored against, so it should not be
+// changed once robots have been inspected.
+type InspectionItem struct {
+	Key  string
+	Name string
 }
 
 // Team maintains information about a team from the perspective of the
@@ -87,6 +170,10 @@ type Team struct {
 	CIDR     string
 	GizmoMAC string
 	DSMAC    string
+
+	// PortalHash is the hash of the password the team uses to
+	// log in to the team portal, or empty if they don't have one.
+	PortalHash string
 }
 
 // FMSConfig contains all the data that's necessary to setup the FMS and
@@ -131,6 +218,14 @@ type FMSConfig struct {
 
 	Integrations IntegrationSlice
 
+	// IntegrationSettings holds the settings for each integration,
+	// keyed by the setting names the integration registered.
+	IntegrationSettings map[Integration]map[string]string
+
+	// Webhooks are notified of field events when the webhook
+	// integration is enabled.
+	Webhooks []*Webhook
+
 	InfrastructureVisible bool
 	InfrastructureSSID    string
 	InfrastructurePSK     string
@@ -149,6 +244,33 @@ type FMSConfig struct {
 	CompatFirmwareVersions string
 	CompatDSBootmodes      string
 	CompatDSVersions       string
+
+	// Alert thresholds control when the FMS raises alerts about
+	// robots that need attention.  Battery voltage is in volts,
+	// RSSI is in dBm, and control frame age is in seconds.  They
+	// are nil until defaults are filled in, since zero is a
+	// threshold that can be chosen on purpose.
+	AlertBatteryVoltage  *float64
+	AlertRSSI            *int
+	AlertControlFrameAge *float64
+
+	// Scoring enables the built-in scorekeeping for events that
+	// don't have a scoring system of their own.  Each element
+	// is counted separately for every quadrant in a match.
+	ScoringEnabled  bool
+	ScoringElements []*ScoringElement
+
+	// InspectionItems is the checklist that every robot must
+	// pass.  InspectionPolicy controls what happens when a team
+	// that hasn't passed inspection is mapped to a field, and can
+	// be 'OFF', 'WARN', or 'REJECT'.  Fields that map
+	// automatically are for practice and are never checked.
+	InspectionItems  []*InspectionItem
+	InspectionPolicy string
+
+	// TeamPortalEnabled allows teams to log in and see their own
+	// schedule, match history, and network credentials.
+	TeamPortalEnabled bool
 }
 
 // Integration is an enum type for things that can talk to the Gizmo
@@ -159,8 +281,21 @@ type Integration int
 // that work on the various integrations.
 type IntegrationSlice []Integration
 
+// These are the IDs of the integrations that are built into the FMS.
+// Other integrations must use IDs that don't collide with them.
 const (
 	// IntegrationPCSM provides API endpoints for the BEST
 	// Robotics PCSM to control the match mapping.
 	IntegrationPCSM Integration = iota
+
+	// IntegrationMQTT publishes telemetry and field state to an
+	// MQTT broker.
+	IntegrationMQTT
+
+	// IntegrationWebhooks posts field events to HTTP endpoints.
+	IntegrationWebhooks
+
+	// IntegrationSchedule follows a schedule published by a
+	// remote server.
+	IntegrationSchedule
 )
@@ -354,7 +354,7 @@ func (c *FMSConfig) setFields() error {
 func (c *FMSConfig) setIntegrations() error {
 	prompt := &survey.MultiSelect{
 		Message: "Select Integrations",
-		Options: allIntegrations.ToStrings(),
+		Options: allIntegrations().ToStrings(),
 		Default: c.Integrations.ToStrings(),
 	}
 
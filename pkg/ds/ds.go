@@ -29,6 +29,9 @@ const (
 	locRate  = time.Second * 3
 	metaRate = time.Second * 5
 	cfgRate  = time.Second * 5
+
+	telemetryRate    = time.Second
+	telemetryBacklog = 64
 )
 
 // New returns a configured driverstation.
@@ -36,6 +39,8 @@ func New(opts ...Option) *DriverStation {
 	d := new(DriverStation)
 	d.l = hclog.NewNullLogger()
 	d.stop = make(chan struct{})
+	d.telemetry = make(chan metrics.Sample, telemetryBacklog)
+	d.link = new(linkTracker)
 	d.localFieldConfig()
 
 	for _, o := range opts {
@@ -43,6 +48,12 @@ func New(opts ...Option) *DriverStation {
 	}
 
 	d.sc = sysconf.New(sysconf.WithFS(efs), sysconf.WithLogger(d.l))
+	d.m = metrics.New(
+		metrics.WithLogger(d.l),
+		metrics.WithTTL(time.Duration(d.cfg.MetricsTTL)*time.Second),
+		metrics.WithOfflineFunc(d.gizmoOffline),
+	)
+	d.health = newHealth(d.m.Registry())
 	return d
 }
 
@@ -64,6 +75,7 @@ func (ds *DriverStation) Run() error {
 	go ds.doLocation()
 	go ds.udpServlet()
 	go ds.doFMSLifecycle()
+	go ds.doRadioStats()
 
 	ds.doGamepad()
 	return nil
@@ -103,17 +115,18 @@ func (ds *DriverStation) udpServlet() error {
 		return err
 	}
 
-	metrics := metrics.New(metrics.WithLogger(ds.l))
+	m := ds.m
 
-	go metrics.BuiltinWebserver(":8080")
-	go metrics.StartFlusher()
+	go m.BuiltinWebserver(":8080")
+	go m.StartFlusher()
 	go func() {
 		<-ds.stop
-		metrics.Shutdown()
+		m.Shutdown()
 		conn.Close()
 	}()
 
 	buf := make([]byte, 1024)
+	teamNum := fmt.Sprintf("%d", ds.cfg.Team)
 	ds.l.Info("Starting UDP Servlet")
 	for {
 		n, _, err := conn.ReadFromUDP(buf)
@@ -129,10 +142,35 @@ func (ds *DriverStation) udpServlet() error {
 		switch rune(buf[0]) {
 		case 'S':
 			// Status Report
-			metrics.ParseReport(fmt.Sprintf("%d", ds.cfg.Team), buf[1:n])
+			report, err := m.ParseReport(teamNum, buf[1:n])
+			if err != nil {
+				continue
+			}
+			if rtt, ok := ds.link.ack(report.LastSeq); ok {
+				m.ObserveControlRTT(teamNum, rtt)
+			}
+			if loss, ok := ds.link.report(report); ok {
+				m.ObserveControlLoss(teamNum, loss)
+			}
+			select {
+			case ds.telemetry <- metrics.Sample{Time: time.Now(), Report: report, Link: ds.link.current()}:
+			default:
+				// The FMS isn't keeping up or isn't
+				// there, drop the sample.
+			}
 		case 'M':
 			// BuildInfo Report
 			ds.gizmoMetaCallback(buf[1:n])
+		case 'A':
+			// Control Frame Ack
+			ack := controlAck{}
+			if err := json.Unmarshal(buf[1:n], &ack); err != nil {
+				ds.l.Debug("Bad control ack", "error", err)
+				continue
+			}
+			if rtt, ok := ds.link.ack(ack.Seq); ok {
+				m.ObserveControlRTT(teamNum, rtt)
+			}
 		}
 	}
 
@@ -156,7 +194,7 @@ func (ds *DriverStation) doLocation() error {
 			ds.l.Info("Stopped publishing location data")
 			return nil
 		case <-ticker.C:
-			dog.Feed()
+			ds.health.feed(dog)
 			ds.l.Trace("Location Tick")
 			vals := struct {
 				Field    int
@@ -187,10 +225,12 @@ func (ds *DriverStation) doGamepad() error {
 
 	jsc := gamepad.NewJSController(gamepad.WithLogger(ds.l))
 	retryFunc := func() error {
+		ds.health.gamepadRebinds.Inc()
 		if err := jsc.Rebind(); err != nil {
 			ds.l.Warn("Rebind failed", "error", err)
 			return err
 		}
+		ds.health.setGamepadBound(true)
 		return nil
 	}
 
@@ -200,10 +240,14 @@ func (ds *DriverStation) doGamepad() error {
 			ds.l.Error("Permanent error encountered while rebinding", "error", err)
 			return err
 		}
+	} else {
+		ds.health.setGamepadBound(true)
 	}
 	defer jsc.Close()
+	defer ds.health.setGamepadBound(false)
 
 	ticker := time.NewTicker(ctrlRate)
+	lastTick := time.Now()
 	ds.l.Info("Starting gamepad pusher")
 	buf := new(bytes.Buffer)
 	for {
@@ -212,20 +256,26 @@ func (ds *DriverStation) doGamepad() error {
 			ticker.Stop()
 			ds.l.Info("Stopped publishing control data")
 			return nil
-		case <-ticker.C:
+		case now := <-ticker.C:
 			ds.l.Trace("Control loop tick")
-			dog.Feed()
+			ds.health.controlJitter.Observe((now.Sub(lastTick) - ctrlRate).Abs().Seconds())
+			lastTick = now
+			ds.health.feed(dog)
 			vals, err := jsc.GetState()
 			if err != nil {
 				ds.l.Warn("Error retrieving controller state", "error", err)
+				ds.health.setGamepadBound(false)
 				if err := backoff.Retry(retryFunc, backoff.NewConstantBackOff(time.Second*3)); err != nil {
 					ds.l.Error("Permanent error encountered while rebinding", "error", err)
 					return err
 				}
 			}
 
+			frame := ds.link.next()
+			frame.Values = *vals
+
 			buf.WriteRune('C')
-			if err := json.NewEncoder(buf).Encode(vals); err != nil {
+			if err := json.NewEncoder(buf).Encode(frame); err != nil {
 				ds.l.Debug("Error publishing message for team", "error", err)
 			}
 			buf.WriteTo(ds.c)
@@ -261,18 +311,22 @@ func (ds *DriverStation) doFMSLifecycle() error {
 			resp, err := cl.Do(req)
 			if err != nil {
 				ds.l.Trace("Error calling FMS config endpoint", "error", err)
+				ds.health.cfgFailures.Inc()
 				continue
 			}
 			if resp.StatusCode != 200 {
 				ds.l.Trace("Wrong code from FMS config endpoint", "code", resp.StatusCode)
+				ds.health.cfgFailures.Inc()
 				continue
 			}
 			if err := ds.cfgCallback(resp.Body); err != nil {
 				ds.l.Error("Error parsing config from FMS", "error", err)
+				ds.health.cfgFailures.Inc()
 				continue
 			}
 			ticker.Stop()
 			go ds.doMetaReport()
+			go ds.doTelemetryForward()
 		}
 	}
 }
@@ -297,6 +351,7 @@ func (ds *DriverStation) doMetaReport() error {
 			vals := &config.DSMeta{
 				Version:  buildinfo.Version,
 				Bootmode: os.Getenv("GIZMO_BOOTMODE"),
+				Health:   ds.health.current(),
 			}
 
 			if vals.Bootmode == "" {
@@ -320,6 +375,73 @@ func (ds *DriverStation) doMetaReport() error {
 	}
 }
 
+// doTelemetryForward batches up the reports from the Gizmo and sends
+// them to the FMS so that it has visibility even if it can't reach
+// the metrics endpoint on the driver's station.
+func (ds *DriverStation) doTelemetryForward() error {
+	cl := &http.Client{Timeout: time.Second}
+	reportURL := &url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("%s:8080", ds.cfg.FieldIP),
+		Path:   fmt.Sprintf("/gizmo/ds/%d/telemetry", ds.cfg.Team),
+	}
+
+	ticker := time.NewTicker(telemetryRate)
+	ds.l.Info("Starting Telemetry Forwarder")
+	batch := []metrics.Sample{}
+	for {
+		select {
+		case <-ds.stop:
+			ticker.Stop()
+			ds.l.Info("Stopped telemetry forwarder")
+			return nil
+		case s := <-ds.telemetry:
+			batch = append(batch, s)
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+
+			data, err := json.Marshal(batch)
+			if err != nil {
+				ds.l.Debug("Error marshalling telemetry", "error", err)
+				continue
+			}
+			batch = batch[:0]
+
+			req, _ := http.NewRequest(http.MethodPost, reportURL.String(), bytes.NewBuffer(data))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(metrics.SentHeader, time.Now().Format(time.RFC3339Nano))
+			resp, err := cl.Do(req)
+			if err != nil {
+				ds.l.Debug("Could not forward telemetry", "error", err)
+				continue
+			}
+			resp.Body.Close()
+		}
+	}
+}
+
+// gizmoOffline is called when the Gizmo has stopped sending status
+// reports.  The FMS is told so that it can let everyone else know.
+func (ds *DriverStation) gizmoOffline(team string) {
+	ds.l.Warn("Gizmo has stopped reporting", "team", team)
+	ds.link.reset()
+
+	c := &http.Client{Timeout: time.Second}
+	reportURL := &url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("%s:8080", ds.cfg.FieldIP),
+		Path:   fmt.Sprintf("/gizmo/robot/%d/offline", ds.cfg.Team),
+	}
+	resp, err := c.Post(reportURL.String(), "application/json", nil)
+	if err != nil {
+		ds.l.Debug("Could not report gizmo offline", "error", err)
+		return
+	}
+	resp.Body.Close()
+}
+
 func (ds *DriverStation) gizmoMetaCallback(buf []byte) {
 	c := &http.Client{Timeout: time.Second}
 	reportURL := &url.URL{
@@ -366,6 +488,7 @@ func (ds *DriverStation) reconfigureRadio() error {
 	}
 
 	ds.l.Info("Reconfiguring DS Radio", "mode", ds.fCfg.RadioMode, "channel", channel)
+	ds.health.setRadio(ds.fCfg.RadioMode, channel)
 	ctx := map[string]string{
 		"NetSSID": ds.cfg.NetSSID,
 		"NetPSK":  ds.cfg.NetPSK,
@@ -0,0 +1,262 @@
+package ds
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vishvananda/netlink"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+	"github.com/gizmo-platform/gizmo/pkg/watchdog"
+)
+
+// The driver's station exports metrics about its own health alongside
+// the robot metrics so that it's possible to tell whether a problem
+// is with the robot or with the driver's station that is controlling
+// it.
+
+const (
+	radioRate = time.Second * 5
+
+	// linkFlapFile is where linkmon records the number of times
+	// that eth0 has gone down.  linkmon restarts the driver's
+	// station when this happens, so the count can't be kept in
+	// memory here.
+	linkFlapFile = "/run/gizmo-linkflaps"
+)
+
+// health binds the metrics for the driver's station itself.
+type health struct {
+	gamepadBound   prometheus.Gauge
+	gamepadRebinds prometheus.Counter
+	controlJitter  prometheus.Histogram
+	watchdogMargin *prometheus.HistogramVec
+	stations       prometheus.Gauge
+	gizmoSignal    prometheus.Gauge
+	cfgFailures    prometheus.Counter
+	radio          *prometheus.GaugeVec
+
+	// The current state is also kept so that it can be reported
+	// to the FMS.
+	mutex sync.Mutex
+	state config.DSHealth
+}
+
+func newHealth(r prometheus.Registerer) *health {
+	h := &health{
+		gamepadBound: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "gizmo",
+			Subsystem: "ds",
+			Name:      "gamepad_bound",
+			Help:      "Gamepad is bound and being read.",
+		}),
+		gamepadRebinds: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "gizmo",
+			Subsystem: "ds",
+			Name:      "gamepad_rebind_attempts_total",
+			Help:      "Attempts made to rebind the gamepad.",
+		}),
+		controlJitter: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "gizmo",
+			Subsystem: "ds",
+			Name:      "control_loop_jitter_seconds",
+			Help:      "Deviation of the control loop from its intended rate.",
+			Buckets:   []float64{0.001, 0.002, 0.005, 0.01, 0.025, 0.05, 0.1},
+		}),
+		watchdogMargin: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gizmo",
+			Subsystem: "ds",
+			Name:      "watchdog_margin_seconds",
+			Help:      "Time remaining on a watchdog when it was fed.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 0.75, 0.9, 1, 2.5, 5, 7.5, 10},
+		}, []string{"dog"}),
+		stations: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "gizmo",
+			Subsystem: "ds",
+			Name:      "hostapd_stations",
+			Help:      "Stations associated with the driver's station radio.",
+		}),
+		gizmoSignal: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "gizmo",
+			Subsystem: "ds",
+			Name:      "gizmo_signal_dbm",
+			Help:      "Signal strength of the Gizmo as seen by the driver's station radio.",
+		}),
+		cfgFailures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "gizmo",
+			Subsystem: "ds",
+			Name:      "fms_config_failures_total",
+			Help:      "Failed attempts to fetch configuration from the FMS.",
+		}),
+		radio: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gizmo",
+			Subsystem: "ds",
+			Name:      "radio_info",
+			Help:      "Current radio mode and channel, always 1.",
+		}, []string{"mode", "channel"}),
+	}
+
+	r.MustRegister(
+		h.gamepadBound,
+		h.gamepadRebinds,
+		h.controlJitter,
+		h.watchdogMargin,
+		h.stations,
+		h.gizmoSignal,
+		h.cfgFailures,
+		h.radio,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: "gizmo",
+			Subsystem: "ds",
+			Name:      "link_flaps_total",
+			Help:      "Times that eth0 has gone down as seen by linkmon.",
+		}, func() float64 { return float64(linkFlaps()) }),
+	)
+	return h
+}
+
+func (h *health) setGamepadBound(bound bool) {
+	h.mutex.Lock()
+	h.state.GamepadBound = bound
+	h.mutex.Unlock()
+
+	if bound {
+		h.gamepadBound.Set(1)
+	} else {
+		h.gamepadBound.Set(0)
+	}
+}
+
+func (h *health) setRadio(mode, channel string) {
+	h.mutex.Lock()
+	h.state.RadioMode = mode
+	h.state.RadioChannel = channel
+	h.mutex.Unlock()
+
+	h.radio.Reset()
+	h.radio.WithLabelValues(mode, channel).Set(1)
+}
+
+func (h *health) setStations(count, signal int) {
+	h.mutex.Lock()
+	h.state.Stations = count
+	h.state.GizmoSignal = signal
+	h.mutex.Unlock()
+
+	h.stations.Set(float64(count))
+	h.gizmoSignal.Set(float64(signal))
+}
+
+// feed feeds the dog and records how close it came to biting.
+func (h *health) feed(d *watchdog.Dog) {
+	h.watchdogMargin.WithLabelValues(d.Name()).Observe(d.Margin().Seconds())
+	d.Feed()
+}
+
+// current returns the state that is reported to the FMS.
+func (h *health) current() config.DSHealth {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	s := h.state
+	s.LinkFlaps = linkFlaps()
+	return s
+}
+
+// doRadioStats periodically checks on the stations that are
+// associated with the driver's station radio.
+func (ds *DriverStation) doRadioStats() {
+	ticker := time.NewTicker(radioRate)
+	gizmoIP := net.IPv4(10, byte(ds.cfg.Team/100), byte(ds.cfg.Team%100), 3)
+
+	for {
+		select {
+		case <-ds.stop:
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			if ds.fCfg.RadioMode != "DS" {
+				ds.health.setStations(0, 0)
+				continue
+			}
+
+			stations, err := hostapdStations()
+			if err != nil {
+				ds.l.Debug("Could not retrieve hostapd stations", "error", err)
+				continue
+			}
+
+			signal := 0
+			if mac, err := neighborMAC(gizmoIP); err == nil {
+				signal = stations[mac.String()]
+			}
+			ds.health.setStations(len(stations), signal)
+		}
+	}
+}
+
+// hostapdStations returns the signal strength for every station
+// associated with the radio, keyed by MAC address.
+func hostapdStations() (map[string]int, error) {
+	out, err := exec.Command("hostapd_cli", "-i", "wlan0", "all_sta").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	stations := make(map[string]int)
+	current := ""
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if mac, err := net.ParseMAC(line); err == nil {
+			current = mac.String()
+			stations[current] = 0
+			continue
+		}
+		if current == "" || !strings.HasPrefix(line, "signal=") {
+			continue
+		}
+		if signal, err := strconv.Atoi(strings.TrimPrefix(line, "signal=")); err == nil {
+			stations[current] = signal
+		}
+	}
+	return stations, scanner.Err()
+}
+
+// neighborMAC looks up the MAC address for an IP in the neighbor
+// table.
+func neighborMAC(ip net.IP) (net.HardwareAddr, error) {
+	neighs, err := netlink.NeighList(0, netlink.FAMILY_V4)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range neighs {
+		if n.IP.Equal(ip) && n.HardwareAddr != nil {
+			return n.HardwareAddr, nil
+		}
+	}
+	return nil, errors.New("no neighbor with that address")
+}
+
+// RecordLinkFlap is called by linkmon each time eth0 goes down.
+func RecordLinkFlap() error {
+	return os.WriteFile(linkFlapFile, []byte(strconv.Itoa(linkFlaps()+1)), 0644)
+}
+
+func linkFlaps() int {
+	buf, err := os.ReadFile(linkFlapFile)
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(buf)))
+	return n
+}
@@ -0,0 +1,114 @@
+package ds
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gizmo-platform/gizmo/pkg/gamepad"
+	"github.com/gizmo-platform/gizmo/pkg/metrics"
+)
+
+// linkWindow is the number of control frames whose send time is
+// remembered.  At the control rate this is a little over 6 seconds,
+// which is far longer than any ack that is still useful.
+const linkWindow = 256
+
+// controlFrame is what is actually sent to the Gizmo.  The sequence
+// number and send time are added alongside the gamepad values so that
+// firmware which doesn't know about them can ignore them.
+type controlFrame struct {
+	gamepad.Values
+
+	Seq    uint32
+	SentAt int64
+}
+
+// controlAck is sent by the Gizmo to echo the most recent control
+// frame that it has received.
+type controlAck struct {
+	Seq uint32
+}
+
+// linkTracker keeps track of control frames that have been sent so
+// that acks and status reports from the Gizmo can be turned into
+// loss and latency figures.
+type linkTracker struct {
+	sync.Mutex
+
+	seq  uint32
+	sent [linkWindow]time.Time
+
+	// The sequence number and received count from the previous
+	// status report, used to compute loss between reports.
+	haveReport bool
+	reportSeq  uint32
+	reportRecv int32
+
+	stats metrics.LinkStats
+}
+
+// next allocates a sequence number for a control frame that is about
+// to be sent.
+func (t *linkTracker) next() controlFrame {
+	t.Lock()
+	defer t.Unlock()
+
+	t.seq++
+	now := time.Now()
+	t.sent[t.seq%linkWindow] = now
+	return controlFrame{Seq: t.seq, SentAt: now.UnixMilli()}
+}
+
+// ack records that the Gizmo has seen the given sequence number and
+// returns the round trip time if the frame is recent enough to still
+// be known.
+func (t *linkTracker) ack(seq uint32) (time.Duration, bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	if seq == 0 || seq > t.seq || t.seq-seq >= linkWindow {
+		return 0, false
+	}
+	rtt := time.Since(t.sent[seq%linkWindow])
+	t.stats.RTT = rtt.Seconds()
+	return rtt, true
+}
+
+// report compares a status report against the previous one to work
+// out what fraction of the frames sent in between were received.
+func (t *linkTracker) report(r metrics.Report) (float64, bool) {
+	t.Lock()
+	defer t.Unlock()
+
+	prevSeq, prevRecv, ok := t.reportSeq, t.reportRecv, t.haveReport
+	t.reportSeq, t.reportRecv, t.haveReport = r.LastSeq, r.ControlFramesReceived, r.LastSeq != 0
+
+	// If the Gizmo rebooted its counters will have gone
+	// backwards, and there's nothing to compare against.
+	if !ok || r.LastSeq <= prevSeq || r.ControlFramesReceived < prevRecv {
+		return 0, false
+	}
+
+	sent := float64(r.LastSeq - prevSeq)
+	recv := float64(r.ControlFramesReceived - prevRecv)
+	loss := min(max(1-recv/sent, 0), 1)
+	t.stats.Loss = loss
+	return loss, true
+}
+
+// current returns the most recently computed link statistics.
+func (t *linkTracker) current() metrics.LinkStats {
+	t.Lock()
+	defer t.Unlock()
+	return t.stats
+}
+
+// reset forgets the previous report and statistics, which are
+// meaningless once the Gizmo has gone offline.
+func (t *linkTracker) reset() {
+	t.Lock()
+	defer t.Unlock()
+
+	t.haveReport = false
+	t.stats = metrics.LinkStats{}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/hashicorp/go-hclog"
 
 	"github.com/gizmo-platform/gizmo/pkg/config"
+	"github.com/gizmo-platform/gizmo/pkg/metrics"
 	"github.com/gizmo-platform/gizmo/pkg/sysconf"
 )
 
@@ -23,6 +24,15 @@ type DriverStation struct {
 
 	sc *sysconf.SysConf
 
+	// telemetry holds reports from the Gizmo until they can be
+	// forwarded to the FMS.
+	telemetry chan metrics.Sample
+
+	link *linkTracker
+
+	m      *metrics.Metrics
+	health *health
+
 	quit bool
 
 	stop chan struct{}
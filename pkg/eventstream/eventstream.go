@@ -79,3 +79,37 @@ func (es *EventStream) PublishFileFetch(file string) {
 	}
 	es.publish(bytes)
 }
+
+// PublishAlert pushes an alert state change into the event stream.
+func (es *EventStream) PublishAlert(id string, team int, state, msg string) {
+	e := EventAlert{
+		Type:    EventTypeAlert,
+		ID:      id,
+		Team:    team,
+		State:   state,
+		Message: msg,
+	}
+
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		es.l.Warn("Error marshaling error", "error", err)
+		return
+	}
+	es.publish(bytes)
+}
+
+// PublishRobotState pushes a robot state change into the event stream.
+func (es *EventStream) PublishRobotState(team int, state string) {
+	e := EventRobotState{
+		Type:  EventTypeRobotState,
+		Team:  team,
+		State: state,
+	}
+
+	bytes, err := json.Marshal(e)
+	if err != nil {
+		es.l.Warn("Error marshaling robot state", "error", err)
+		return
+	}
+	es.publish(bytes)
+}
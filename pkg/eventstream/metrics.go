@@ -0,0 +1,36 @@
+package eventstream
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	subscribersDesc = prometheus.NewDesc(
+		"gizmo_fms_eventstream_subscribers",
+		"Number of clients currently subscribed to the event stream.",
+		nil, nil,
+	)
+	droppedDesc = prometheus.NewDesc(
+		"gizmo_fms_eventstream_dropped_total",
+		"Subscribers that were disconnected for not keeping up with messages.",
+		nil, nil,
+	)
+)
+
+// Describe implements prometheus.Collector so that the EventStream
+// can be registered directly.
+func (es *EventStream) Describe(ch chan<- *prometheus.Desc) {
+	ch <- subscribersDesc
+	ch <- droppedDesc
+}
+
+// Collect implements prometheus.Collector.
+func (es *EventStream) Collect(ch chan<- prometheus.Metric) {
+	es.subscribersMutex.Lock()
+	subscribers := len(es.subscribers)
+	dropped := es.dropped
+	es.subscribersMutex.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(subscribersDesc, prometheus.GaugeValue, float64(subscribers))
+	ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(dropped))
+}
@@ -24,3 +24,9 @@ func (ns *NullStream) PublishActionComplete(_ string) {}
 
 // PublishFileFetch discards all filenames.
 func (ns *NullStream) PublishFileFetch(_ string) {}
+
+// PublishAlert discards all alerts.
+func (ns *NullStream) PublishAlert(_ string, _ int, _, _ string) {}
+
+// PublishRobotState discards all robot state changes.
+func (ns *NullStream) PublishRobotState(_ int, _ string) {}
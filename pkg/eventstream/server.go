@@ -25,6 +25,10 @@ type EventStream struct {
 
 	subscribersMutex sync.Mutex
 	subscribers      map[*subscriber]struct{}
+
+	// dropped counts subscribers that were disconnected for
+	// being too slow, and is protected by the subscribersMutex.
+	dropped uint64
 }
 
 // New returns an initialized event streamer ready for use.
@@ -131,6 +135,7 @@ func (es *EventStream) publish(msg []byte) {
 		select {
 		case s.msgs <- msg:
 		default:
+			es.dropped++
 			go s.closeSlow()
 		}
 	}
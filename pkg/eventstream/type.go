@@ -31,6 +31,14 @@ const (
 	// EventTypeFileFetch is fired when a file is successfully
 	// retrieved from a remote source.
 	EventTypeFileFetch
+
+	// EventTypeAlert is fired when an alert is raised,
+	// acknowledged, or cleared.
+	EventTypeAlert
+
+	// EventTypeRobotState is fired when a robot changes state,
+	// such as when it stops reporting.
+	EventTypeRobotState
 )
 
 // EventError contains the underlying error that occured.
@@ -64,3 +72,19 @@ type EventFileFetch struct {
 	Type     EventType
 	Filename string
 }
+
+// EventAlert contains the state of an alert that changed.
+type EventAlert struct {
+	Type    EventType
+	ID      string
+	Team    int
+	State   string
+	Message string
+}
+
+// EventRobotState contains the state that a robot changed to.
+type EventRobotState struct {
+	Type  EventType
+	Team  int
+	State string
+}
@@ -0,0 +1,316 @@
+package fms
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+	"github.com/gizmo-platform/gizmo/pkg/match"
+)
+
+// The alerts engine continuously evaluates a set of rules against
+// every team that is mapped to a field.  When a rule has been failing
+// for long enough an alert is raised, and it stays raised until the
+// rule passes again.  Alerts may be acknowledged by a volunteer, but
+// this only quiets them; the alert isn't cleared until the problem
+// is actually resolved.
+
+const (
+	alertRate = time.Second * 2
+
+	// alertHoldoff is how long a rule must fail before an alert
+	// is raised.  This keeps alerts from firing during the few
+	// seconds it takes for things to connect after a remap.
+	alertHoldoff = time.Second * 10
+
+	alertStateRaised  = "raised"
+	alertStateAcked   = "acknowledged"
+	alertStateCleared = "cleared"
+)
+
+// alertKind identifies which rule an alert came from.
+type alertKind string
+
+const (
+	alertBatteryLow   alertKind = "battery-low"
+	alertRSSIWeak     alertKind = "rssi-weak"
+	alertControlStale alertKind = "control-stale"
+	alertGizmoCompat  alertKind = "gizmo-compat"
+	alertDSCompat     alertKind = "ds-compat"
+	alertDSMissing    alertKind = "ds-missing"
+	alertWrongQuad    alertKind = "wrong-quad"
+)
+
+var (
+	errNoSuchAlert = errors.New("no alert with that ID exists")
+)
+
+// alert is a single problem that has been detected with a team.
+type alert struct {
+	ID      string
+	Kind    alertKind
+	Team    int
+	Quad    string
+	Message string
+	Raised  time.Time
+	Acked   bool
+
+	// Correctable alerts can be fixed by remapping the field
+	// rather than requiring someone to go do something.
+	Correctable bool
+}
+
+// alertInput is everything a rule may look at to decide if there is
+// a problem with a team.
+type alertInput struct {
+	Team   int
+	Quad   string
+	Actual string
+
+	DSConnected    bool
+	DSMeta         config.DSMeta
+	GizmoConnected bool
+	GizmoMeta      config.GizmoMeta
+
+	HasTelemetry bool
+	Telemetry    telemetrySample
+}
+
+// alertRule checks the input and returns true along with a message
+// describing the problem if the rule has failed.
+type alertRule struct {
+	Kind  alertKind
+	Check func(alertInput) (bool, string)
+}
+
+func (f *FMS) alertRules() []alertRule {
+	return []alertRule{
+		{alertBatteryLow, func(in alertInput) (bool, string) {
+			if !in.HasTelemetry || in.Telemetry.BatteryVoltage >= *f.c.AlertBatteryVoltage {
+				return false, ""
+			}
+			return true, fmt.Sprintf("Team %d battery is low (%.2fV)", in.Team, in.Telemetry.BatteryVoltage)
+		}},
+		{alertRSSIWeak, func(in alertInput) (bool, string) {
+			// An RSSI of exactly 0 means the radio hasn't
+			// reported a value yet.
+			if !in.HasTelemetry || in.Telemetry.RSSI == 0 || in.Telemetry.RSSI >= float64(*f.c.AlertRSSI) {
+				return false, ""
+			}
+			return true, fmt.Sprintf("Team %d WiFi signal is weak (%.0f dBm)", in.Team, in.Telemetry.RSSI)
+		}},
+		{alertControlStale, func(in alertInput) (bool, string) {
+			if !in.HasTelemetry || in.Telemetry.ControlFrameAge <= *f.c.AlertControlFrameAge {
+				return false, ""
+			}
+			return true, fmt.Sprintf("Team %d is not receiving control frames (%.2fs old)", in.Team, in.Telemetry.ControlFrameAge)
+		}},
+		{alertGizmoCompat, func(in alertInput) (bool, string) {
+			if !in.GizmoConnected {
+				return false, ""
+			}
+			if !in.GizmoMeta.HWVersionOK(f.c.CompatHardwareVersions) {
+				return true, fmt.Sprintf("Team %d Gizmo hardware %s is not compatible", in.Team, in.GizmoMeta.HardwareVersion)
+			}
+			if !in.GizmoMeta.FWVersionOK(f.c.CompatFirmwareVersions) {
+				return true, fmt.Sprintf("Team %d Gizmo firmware %s is not compatible", in.Team, in.GizmoMeta.FirmwareVersion)
+			}
+			return false, ""
+		}},
+		{alertDSCompat, func(in alertInput) (bool, string) {
+			if !in.DSConnected {
+				return false, ""
+			}
+			if !in.DSMeta.VersionOK(f.c.CompatDSVersions) {
+				return true, fmt.Sprintf("Team %d driver's station version %s is not compatible", in.Team, in.DSMeta.Version)
+			}
+			if !in.DSMeta.BootmodeOK(f.c.CompatDSBootmodes) {
+				return true, fmt.Sprintf("Team %d driver's station boot mode %s is not compatible", in.Team, in.DSMeta.Bootmode)
+			}
+			return false, ""
+		}},
+		{alertDSMissing, func(in alertInput) (bool, string) {
+			// A driver's station in the wrong quad can't
+			// reach the FMS, but that has its own alert.
+			if in.DSConnected || (in.Actual != "" && in.Actual != in.Quad) {
+				return false, ""
+			}
+			return true, fmt.Sprintf("Team %d is mapped to %s but their driver's station is not connected", in.Team, in.Quad)
+		}},
+		{alertWrongQuad, func(in alertInput) (bool, string) {
+			if in.Actual == "" || in.Actual == in.Quad {
+				return false, ""
+			}
+			return true, fmt.Sprintf("Team %d is mapped to %s but plugged into %s", in.Team, quadPort(in.Quad), quadPort(in.Actual))
+		}},
+	}
+}
+
+func (f *FMS) doAlertUpkeep() {
+	ticker := time.NewTicker(alertRate)
+
+	for {
+		select {
+		case <-f.stop:
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			f.evaluateAlerts()
+		}
+	}
+}
+
+func (f *FMS) evaluateAlerts() {
+	m, err := f.tlm.GetCurrentMapping()
+	if err != nil {
+		f.l.Warn("Could not retrieve mapping for alerts", "error", err)
+		return
+	}
+
+	telemetry, err := f.latestTelemetry()
+	if err != nil {
+		f.l.Trace("Could not retrieve telemetry for alerts", "error", err)
+	}
+
+	rules := f.alertRules()
+	failing := make(map[string]struct{})
+	now := time.Now()
+	for team, quad := range m {
+		in := alertInput{Team: team, Quad: quad, Actual: f.actualQuadForTeam(team)}
+
+		f.connectedMutex.RLock()
+		_, in.DSConnected = f.connectedDS[team]
+		_, in.GizmoConnected = f.connectedGizmo[team]
+		f.connectedMutex.RUnlock()
+
+		f.metaMutex.RLock()
+		in.DSMeta = f.dsMeta[team]
+		in.GizmoMeta = f.gizmoMeta[team]
+		f.metaMutex.RUnlock()
+
+		in.Telemetry, in.HasTelemetry = telemetry[team]
+
+		for _, rule := range rules {
+			fail, msg := rule.Check(in)
+			if !fail {
+				continue
+			}
+			id := fmt.Sprintf("%s:%d", rule.Kind, team)
+			failing[id] = struct{}{}
+			f.raiseAlert(now, alert{
+				ID:          id,
+				Kind:        rule.Kind,
+				Team:        team,
+				Quad:        quad,
+				Message:     msg,
+				Correctable: rule.Kind == alertWrongQuad,
+			})
+		}
+	}
+
+	f.alertMutex.Lock()
+	for id := range f.alertsPending {
+		if _, stillFailing := failing[id]; !stillFailing {
+			delete(f.alertsPending, id)
+		}
+	}
+	cleared := []*alert{}
+	for id, a := range f.alerts {
+		if _, stillFailing := failing[id]; !stillFailing {
+			delete(f.alerts, id)
+			cleared = append(cleared, a)
+		}
+	}
+	f.alertMutex.Unlock()
+
+	for _, a := range cleared {
+		f.publishAlert(a, alertStateCleared)
+	}
+}
+
+// raiseAlert raises the alert if it has been pending for long enough,
+// or updates the message if the alert is already raised.
+func (f *FMS) raiseAlert(now time.Time, a alert) {
+	f.alertMutex.Lock()
+	if existing, raised := f.alerts[a.ID]; raised {
+		existing.Message = a.Message
+		f.alertMutex.Unlock()
+		return
+	}
+	first, pending := f.alertsPending[a.ID]
+	if !pending {
+		f.alertsPending[a.ID] = now
+		f.alertMutex.Unlock()
+		return
+	}
+	if now.Sub(first) < alertHoldoff {
+		f.alertMutex.Unlock()
+		return
+	}
+	delete(f.alertsPending, a.ID)
+	a.Raised = now
+	f.alerts[a.ID] = &a
+	f.alertMutex.Unlock()
+
+	f.l.Warn("Alert raised", "id", a.ID, "message", a.Message)
+	f.publishAlert(&a, alertStateRaised)
+	f.notifyWebhooks(webhookAlertRaised, a)
+}
+
+// ackAlert acknowledges an alert, which will keep it from being shown
+// as requiring attention.
+func (f *FMS) ackAlert(id string) error {
+	f.alertMutex.Lock()
+	a, ok := f.alerts[id]
+	if !ok {
+		f.alertMutex.Unlock()
+		return errNoSuchAlert
+	}
+	a.Acked = true
+	aCopy := *a
+	f.alertMutex.Unlock()
+
+	f.publishAlert(&aCopy, alertStateAcked)
+	return nil
+}
+
+// activeAlerts returns all currently raised alerts, oldest first.
+func (f *FMS) activeAlerts() []alert {
+	f.alertMutex.RLock()
+	out := make([]alert, 0, len(f.alerts))
+	for _, a := range f.alerts {
+		out = append(out, *a)
+	}
+	f.alertMutex.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Raised.Before(out[j].Raised)
+	})
+	return out
+}
+
+// alertsForTeam returns the currently raised alerts for a single team.
+func (f *FMS) alertsForTeam(team int) []alert {
+	out := []alert{}
+	for _, a := range f.activeAlerts() {
+		if a.Team == team {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func (f *FMS) publishAlert(a *alert, state string) {
+	f.es.PublishAlert(a.ID, a.Team, state, a.Message)
+
+	err := f.archive.Log(match.Event{
+		Kind:    "alert-" + state,
+		Team:    a.Team,
+		Message: a.Message,
+	})
+	if err != nil && !errors.Is(err, match.ErrNoMatch) {
+		f.l.Warn("Could not record alert", "id", a.ID, "error", err)
+	}
+}
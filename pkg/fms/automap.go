@@ -0,0 +1,152 @@
+package fms
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields that have AutoMap enabled don't need anyone to maintain the
+// map.  When the field reports via LLDP that a rostered team's
+// driver's station is plugged into one of its quads, that team is
+// mapped there.  A team has to be seen in the same place for a little
+// while before the map changes, and has to be gone for quite a bit
+// longer before it is removed, so that a flaky cable or a slow LLDP
+// update doesn't bounce teams on and off the field.  Changes are made
+// in place without starting a match record, since they have nothing to
+// do with the matches on the competition fields.  Quads with an
+// active practice booking belong to the team that booked them, so
+// they are left alone.
+
+const (
+	autoMapRate = time.Second
+
+	// autoMapSettle is how long a team must be seen on a quad
+	// before they are mapped to it.
+	autoMapSettle = time.Second * 5
+
+	// autoMapRelease is how long a team must be missing from a
+	// quad before they are unmapped from it.
+	autoMapRelease = time.Second * 30
+)
+
+// autoMapSighting tracks the team most recently seen on a quad.
+type autoMapSighting struct {
+	Team     int
+	Since    time.Time
+	LastSeen time.Time
+}
+
+func (f *FMS) doAutoMapUpkeep() {
+	ticker := time.NewTicker(autoMapRate)
+	sightings := make(map[string]*autoMapSighting)
+
+	for {
+		select {
+		case <-f.stop:
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			f.autoMapStep(time.Now(), sightings)
+		}
+	}
+}
+
+// autoMapStep updates the sightings from what the fields currently
+// report and then applies a new mapping if anything on an AutoMap
+// field has settled into a different state.
+func (f *FMS) autoMapStep(now time.Time, sightings map[string]*autoMapSighting) {
+	booked := f.practice.Active()
+	quads := []string{}
+	for _, quad := range f.quads {
+		if _, ok := booked[quad]; ok {
+			continue
+		}
+		if f.quadIsAutoMapped(quad) {
+			quads = append(quads, quad)
+		}
+	}
+	if len(quads) == 0 {
+		return
+	}
+
+	err := f.updateMapping(mapKindAutomatic, func(m map[int]string) bool {
+		return f.autoMapUpdate(now, quads, sightings, m)
+	})
+	if err != nil {
+		f.l.Error("Error applying automap", "error", err)
+	}
+}
+
+// autoMapUpdate makes the changes to the mapping for the quads that
+// are being automapped, returning true if anything changed.
+func (f *FMS) autoMapUpdate(now time.Time, quads []string, sightings map[string]*autoMapSighting, m map[int]string) bool {
+	mappedTo := make(map[string]int, len(m))
+	for team, quad := range m {
+		mappedTo[quad] = team
+	}
+
+	f.dsPresentMutex.RLock()
+	for _, quad := range quads {
+		team, present := f.dsPresent[quad]
+		if _, rostered := f.c.Teams[team]; !present || !rostered {
+			continue
+		}
+		s, ok := sightings[quad]
+		if !ok || s.Team != team {
+			sightings[quad] = &autoMapSighting{Team: team, Since: now, LastSeen: now}
+			continue
+		}
+		s.LastSeen = now
+	}
+	f.dsPresentMutex.RUnlock()
+
+	changed := false
+	for _, quad := range quads {
+		mapped := mappedTo[quad]
+		s, ok := sightings[quad]
+		if !ok {
+			if mapped != 0 {
+				// Someone is already mapped here, either by
+				// hand or from before a restart.  Give them
+				// the benefit of the doubt and start the
+				// release timer.
+				sightings[quad] = &autoMapSighting{Team: mapped, Since: now, LastSeen: now}
+			}
+			continue
+		}
+
+		if now.Sub(s.LastSeen) >= autoMapRelease {
+			delete(sightings, quad)
+			if mapped != 0 && m[mapped] == quad {
+				f.l.Info("Automap releasing team", "team", mapped, "quad", quad)
+				delete(m, mapped)
+				changed = true
+			}
+			continue
+		}
+
+		if s.Team == mapped || s.LastSeen != now || now.Sub(s.Since) < autoMapSettle {
+			continue
+		}
+		if mapped != 0 && m[mapped] == quad {
+			delete(m, mapped)
+		}
+		f.l.Info("Automap assigning team", "team", s.Team, "quad", quad)
+		m[s.Team] = quad
+		changed = true
+	}
+	return changed
+}
+
+// quadIsAutoMapped returns true if the quad belongs to a field that
+// has AutoMap enabled.
+func (f *FMS) quadIsAutoMapped(quad string) bool {
+	fStr := strings.TrimPrefix(strings.SplitN(quad, ":", 2)[0], "field")
+	fNum, err := strconv.Atoi(fStr)
+	if err != nil {
+		return false
+	}
+	field, ok := f.c.Fields[fNum-1]
+	return ok && field.AutoMap
+}
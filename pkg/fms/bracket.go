@@ -0,0 +1,247 @@
+package fms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gizmo-platform/gizmo/pkg/bracket"
+)
+
+// Alliance eliminations are run from a single elimination bracket.
+// Whenever the bracket changes, the next match that is ready to be
+// played is staged automatically so that the field crew only has to
+// commit it once the field is clear.  Each bracket match is played on
+// one field, with the first alliance in the red and blue quadrants
+// and the second in green and yellow.
+
+// bracketQuads are the quadrants that each slot of a bracket match
+// plays from.
+var bracketQuads = [2][]string{{"red", "blue"}, {"green", "yellow"}}
+
+// bracketGenerate is the body of a request to generate a bracket.
+// Alliances are listed in seed order.
+type bracketGenerate struct {
+	Field     int
+	Alliances []bracket.Alliance
+}
+
+type bracketResult struct {
+	Scores [2]int
+}
+
+// bracketSide is one alliance in a bracket match as it is shown on
+// the bracket page.
+type bracketSide struct {
+	Seed  int
+	Teams []int
+	Score int
+	Won   bool
+}
+
+type bracketMatchView struct {
+	bracket.Match
+
+	Sides []bracketSide
+}
+
+// bracketMapping works out the mapping for a bracket match.
+func bracketMapping(b bracket.Bracket, m bracket.Match) map[int]string {
+	out := make(map[int]string)
+	for slot, seed := range m.Alliances {
+		a, ok := b.Alliance(seed)
+		if !ok {
+			continue
+		}
+		for i, team := range a.Teams {
+			out[team] = fmt.Sprintf("field%d:%s", b.Field, bracketQuads[slot][i])
+		}
+	}
+	return out
+}
+
+// stageBracketMatch stages a bracket match in the TLM.  The match
+// name goes with the stage so that the record started when it is
+// committed says which bracket match it was.
+func (f *FMS) stageBracketMatch(b bracket.Bracket, m bracket.Match) error {
+	if err := f.stageMapping(stagedMatch{Name: m.Name}, bracketMapping(b, m)); err != nil {
+		return err
+	}
+	f.l.Info("Staged bracket match", "match", m.Name)
+	f.es.PublishActionComplete(m.Name + " Staged")
+	return nil
+}
+
+// stageNextBracketMatch stages the next match in the bracket, if
+// there is one that is ready to be played.  A stage that came from
+// somewhere other than the bracket and hasn't been committed yet is
+// left alone, since it is most likely a match that the field crew
+// still means to play.
+func (f *FMS) stageNextBracketMatch(b bracket.Bracket) {
+	m, ok := b.Next()
+	if !ok {
+		return
+	}
+	if staged, ok := f.staged(); (!ok || staged.Name == "") && f.stagePending() {
+		f.l.Warn("Not staging bracket match, another match is already staged", "match", m.Name)
+		f.es.PublishError(fmt.Errorf("%s was not staged because another match is already staged", m.Name))
+		return
+	}
+	if err := f.stageBracketMatch(b, m); err != nil {
+		f.l.Warn("Could not stage bracket match", "match", m.Name, "error", err)
+		f.es.PublishError(err)
+	}
+}
+
+func (f *FMS) apiGetBracket(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(f.bracket.Get())
+}
+
+func (f *FMS) apiGenerateBracket(w http.ResponseWriter, r *http.Request) {
+	req := new(bracketGenerate)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if field, ok := f.c.Fields[req.Field-1]; !ok || field.AutoMap {
+		http.Error(w, "eliminations must be played on a field that is mapped by hand", http.StatusBadRequest)
+		return
+	}
+	seen := make(map[int]int)
+	for i, a := range req.Alliances {
+		if len(a.Teams) < 1 || len(a.Teams) > len(bracketQuads[0]) {
+			http.Error(w, fmt.Sprintf("alliance %d must have 1 or %d teams", i+1, len(bracketQuads[0])), http.StatusBadRequest)
+			return
+		}
+		for _, team := range a.Teams {
+			if _, ok := f.c.Teams[team]; !ok {
+				http.Error(w, fmt.Sprintf("team %d is not on the roster", team), http.StatusBadRequest)
+				return
+			}
+			if prev, ok := seen[team]; ok {
+				http.Error(w, fmt.Sprintf("team %d is in alliances %d and %d", team, prev, i+1), http.StatusBadRequest)
+				return
+			}
+			seen[team] = i + 1
+		}
+	}
+
+	b, err := bracket.Generate(req.Field, req.Alliances)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := f.bracket.Replace(b); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+	f.es.PublishActionComplete("Bracket Generation")
+	f.stageNextBracketMatch(b)
+}
+
+func (f *FMS) apiBracketResult(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res := new(bracketResult)
+	if err := json.NewDecoder(r.Body).Decode(res); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, err := f.bracket.Record(id, res.Scores)
+	switch {
+	case err == nil:
+	case errors.Is(err, bracket.ErrNoSuchMatch):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, bracket.ErrNotReady), errors.Is(err, bracket.ErrTie), errors.Is(err, bracket.ErrAlreadyAdvanced):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+	f.es.PublishActionComplete("Bracket Result")
+	f.stageNextBracketMatch(b)
+}
+
+func (f *FMS) apiStageBracketMatch(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b := f.bracket.Get()
+	m, err := b.Match(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if m.Winner != 0 {
+		http.Error(w, "this match has already been played", http.StatusConflict)
+		return
+	}
+	if !m.Ready() {
+		http.Error(w, bracket.ErrNotReady.Error(), http.StatusConflict)
+		return
+	}
+	if err := f.stageBracketMatch(b, m); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+func (f *FMS) uiViewBracket(w http.ResponseWriter, r *http.Request) {
+	b := f.bracket.Get()
+
+	rounds := [][]bracketMatchView{}
+	for _, m := range b.Matches {
+		for len(rounds) < m.Round {
+			rounds = append(rounds, []bracketMatchView{})
+		}
+		v := bracketMatchView{Match: m}
+		for slot, seed := range m.Alliances {
+			side := bracketSide{Seed: seed, Score: m.Scores[slot], Won: seed != 0 && seed == m.Winner}
+			if a, ok := b.Alliance(seed); ok {
+				side.Teams = a.Teams
+			}
+			v.Sides = append(v.Sides, side)
+		}
+		rounds[m.Round-1] = append(rounds[m.Round-1], v)
+	}
+
+	fields := []int{}
+	for _, field := range f.c.Fields {
+		if !field.AutoMap {
+			fields = append(fields, field.ID)
+		}
+	}
+	sort.Ints(fields)
+
+	ctx := pongo2.Context{
+		"bracket": b,
+		"rounds":  rounds,
+		"fields":  fields,
+	}
+	if a, ok := b.Alliance(b.Champion()); ok {
+		ctx["champion"] = a
+	}
+	if m, ok := b.Next(); ok {
+		ctx["next"] = m.ID
+	}
+	f.doTemplate(w, r, "views/admin/bracket.p2", ctx)
+}
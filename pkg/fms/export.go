@@ -0,0 +1,94 @@
+package fms
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gizmo-platform/gizmo/pkg/metrics"
+)
+
+// Telemetry is written into the match archive as a CSV per team so
+// that teams and mentors can look at what their robot did after the
+// event, even if the event didn't run Prometheus and Grafana.
+
+var telemetryCSVHeader = []string{
+	"time",
+	"battery_voltage",
+	"rssi",
+	"control_frame_age_seconds",
+	"wifi_reconnects",
+	"control_loss_ratio",
+	"control_rtt_seconds",
+	"power_board",
+	"power_pico",
+	"power_gpio",
+	"power_servo",
+	"power_bus_a",
+	"power_bus_b",
+	"power_pixels",
+}
+
+// TelemetryCSVName is the name of the artifact that holds a team's
+// telemetry within a match record.
+func TelemetryCSVName(team int) string {
+	return fmt.Sprintf("telemetry-%d.csv", team)
+}
+
+func (s telemetrySample) csvRow() []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	b := func(v bool) string { return strconv.FormatBool(v) }
+
+	return []string{
+		s.Time.Format(time.RFC3339Nano),
+		f(s.BatteryVoltage),
+		f(s.RSSI),
+		f(s.ControlFrameAge),
+		strconv.Itoa(s.WifiReconnects),
+		f(s.ControlLoss),
+		f(s.ControlRTT),
+		b(s.PwrBoard),
+		b(s.PwrPico),
+		b(s.PwrGPIO),
+		b(s.PwrServo),
+		b(s.PwrMainA),
+		b(s.PwrMainB),
+		b(s.PwrPixels),
+	}
+}
+
+// archiveTelemetry writes the samples in the batch that were taken
+// during the current match to the team's telemetry file.
+func (f *FMS) archiveTelemetry(team int, batch []metrics.Sample) {
+	rec, err := f.archive.Current()
+	if err != nil {
+		return
+	}
+	if _, mapped := rec.Mapping[team]; !mapped {
+		return
+	}
+
+	header := new(bytes.Buffer)
+	hw := csv.NewWriter(header)
+	hw.Write(telemetryCSVHeader)
+	hw.Flush()
+
+	data := new(bytes.Buffer)
+	w := csv.NewWriter(data)
+	for _, s := range batch {
+		if s.Time.Before(rec.Mapped) {
+			continue
+		}
+		w.Write(newTelemetrySample(s).csvRow())
+	}
+	w.Flush()
+	if data.Len() == 0 {
+		return
+	}
+
+	if err := f.archive.AppendArtifact(rec.ID, TelemetryCSVName(team), header.Bytes(), data.Bytes()); err != nil {
+		f.l.Warn("Could not archive telemetry", "team", team, "record", rec.ID, "error", err)
+	}
+}
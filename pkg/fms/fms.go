@@ -3,6 +3,7 @@ package fms
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"io/fs"
 	nhttp "net/http"
@@ -13,10 +14,12 @@ import (
 	"github.com/flosch/pongo2/v6"
 	"github.com/go-chi/chi/v5"
 	"github.com/hashicorp/go-hclog"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/gizmo-platform/gizmo/pkg/config"
 	"github.com/gizmo-platform/gizmo/pkg/docs"
 	"github.com/gizmo-platform/gizmo/pkg/http"
+	"github.com/gizmo-platform/gizmo/pkg/metrics"
 
 	"github.com/the-maldridge/authware"
 	// We use htpasswd because authenticating using other means
@@ -36,6 +39,7 @@ func New(opts ...Option) (*FMS, error) {
 
 	x := new(FMS)
 	r := chi.NewRouter()
+	r.Use(x.metricsMiddleware)
 	x.l = hclog.NewNullLogger()
 	x.tpl = pongo2.NewSet("html", ldr)
 	x.connectedDS = make(map[int]time.Time)
@@ -46,14 +50,50 @@ func New(opts ...Option) (*FMS, error) {
 	x.metaMutex = new(sync.RWMutex)
 	x.dsPresent = make(map[string]int)
 	x.dsPresentMutex = new(sync.RWMutex)
+	x.alerts = make(map[string]*alert)
+	x.alertsPending = make(map[string]time.Time)
+	x.alertMutex = new(sync.RWMutex)
+	x.robotTelemetry = make(map[int]metrics.Sample)
+	x.telemetryHistory = make(map[int][]telemetrySample)
+	x.robotTelemetryMutex = new(sync.RWMutex)
+	x.stagedMutex = new(sync.RWMutex)
+	x.mapMutex = new(sync.Mutex)
+	x.portalSessions = make(map[string]portalSession)
+	x.portalMutex = new(sync.RWMutex)
+	x.connectionLog = make(map[int][]connectionEvent)
+	x.connectionMutex = new(sync.RWMutex)
 	x.stop = make(chan struct{})
+	x.promRegistry = prometheus.NewRegistry()
 
 	for _, o := range opts {
 		if err := o(x); err != nil {
 			return nil, err
 		}
 	}
+	if x.archive == nil {
+		return nil, errors.New("a match archive is required")
+	}
+	if x.quals == nil {
+		return nil, errors.New("a schedule store is required")
+	}
+	if x.bracket == nil {
+		return nil, errors.New("a bracket store is required")
+	}
+	if x.practice == nil {
+		return nil, errors.New("a practice store is required")
+	}
+	if x.inspections == nil {
+		return nil, errors.New("an inspection store is required")
+	}
 	x.l.Debug("Quads Configured", "quads", x.quads)
+	for _, i := range integrations {
+		if i.Init != nil {
+			i.Init(x)
+		}
+	}
+	x.metrics = newFMSMetrics(x.promRegistry)
+	x.promRegistry.MustRegister(robotCollector{x})
+	x.promRegistry.MustRegister(robotExtraCollector{x})
 
 	var err error
 	x.s, err = http.NewServer(http.WithLogger(x.l), http.WithStartupWG(x.swg))
@@ -74,27 +114,29 @@ func New(opts ...Option) (*FMS, error) {
 	sfs, _ := fs.Sub(uifs, "ui")
 	r.Handle("/static/*", nhttp.FileServer(nhttp.FS(sfs)))
 	r.Get("/login", x.uiViewLogin)
-	r.Post("/login", basic.LoginFormHandler("username", "password", "/ui/admin"))
-	r.Get("/logout", basic.LogoutHandler("/"))
+	r.Post("/login", x.portalLoginHandler(basic.LoginFormHandler("username", "password", "/ui/admin")))
+	r.Get("/logout", x.portalLogoutHandler(basic.LogoutHandler("/")))
 	r.Route("/gizmo/ds", func(r chi.Router) {
 		r.Get("/{id}/config", x.gizmoConfig)
 		r.Post("/{id}/meta", x.gizmoDSMetaReport)
+		r.Post("/{id}/telemetry", x.gizmoDSTelemetryReport)
 	})
 	r.Route("/gizmo/robot", func(r chi.Router) {
 		r.Post("/{id}/meta", x.gizmoMetaReport)
+		r.Post("/{id}/offline", x.gizmoOfflineReport)
 	})
-	r.Route("/admin", func(r chi.Router) {
-		r.Post("/map/pcsm", x.remapTeamsPCSM)
-	})
+	x.mountIntegrations(r)
 
 	r.Route("/api", func(r chi.Router) {
-		r.Get("/config", x.apiGetConfig)
+		r.With(basic.MultiAuthHandler()).Get("/config", x.apiGetConfig)
 		r.Get("/eventstream", x.es.Handler)
 		r.Route("/field", func(r chi.Router) {
 			r.Use(basic.MultiAuthHandler())
 			r.Get("/configured-quads", x.apiGetConfiguredQuads)
 			r.Get("/present/{field}/{quad}", x.apiGetTeamPresent)
 			r.Get("/present", x.apiGetTeamPresentAll)
+			r.Get("/correct/{team}", x.apiGetQuadCorrection)
+			r.Post("/correct/{team}", x.apiApplyQuadCorrection)
 		})
 		r.Route("/map", func(r chi.Router) {
 			r.Use(basic.MultiAuthHandler())
@@ -114,7 +156,13 @@ func New(opts ...Option) (*FMS, error) {
 			r.Post("/update-wifi", x.apiUpdateNetWifi)
 			r.Post("/update-advanced-net", x.apiUpdateAdvancedNet)
 			r.Post("/update-integrations", x.apiUpdateIntegrations)
+			r.Post("/integration/{slug}", x.apiUpdateIntegrationSettings)
 			r.Post("/update-compatver", x.apiUpdateCompatVer)
+			r.Post("/update-alerts", x.apiUpdateAlertThresholds)
+			r.Post("/update-scoring", x.apiUpdateScoring)
+			r.Post("/update-inspection", x.apiUpdateInspection)
+			r.Post("/update-portal", x.apiUpdatePortal)
+			r.Post("/portal-logins", x.apiGeneratePortalLogins)
 
 			r.Route("/field", func(r chi.Router) {
 				r.Post("/", x.apiFieldAdd)
@@ -145,8 +193,74 @@ func New(opts ...Option) (*FMS, error) {
 			r.Post("/reconcile", x.apiNetReconcile)
 		})
 
+		r.Route("/alerts", func(r chi.Router) {
+			r.Use(basic.MultiAuthHandler())
+			r.Get("/", x.apiGetAlerts)
+			r.Post("/{id}/ack", x.apiAckAlert)
+		})
+
+		r.Route("/matches", func(r chi.Router) {
+			r.Use(basic.MultiAuthHandler())
+			r.Get("/{id}/telemetry/{team}", x.apiGetMatchTelemetry)
+		})
+
 		r.Route("/display", func(r chi.Router) {
 			r.Get("/field-hud", x.apiFieldHUD)
+			r.Get("/practice-board", x.apiPracticeBoard)
+		})
+
+		r.Route("/integrations", func(r chi.Router) {
+			r.Use(basic.MultiAuthHandler())
+			x.mountIntegrationAPI(r)
+		})
+
+		r.Route("/schedule", func(r chi.Router) {
+			r.Use(basic.MultiAuthHandler())
+			r.Get("/", x.apiGetQualSchedule)
+			r.Get("/csv", x.apiGetQualScheduleCSV)
+			r.Post("/generate", x.apiGenerateQualSchedule)
+			r.Post("/{number}/stage", x.apiStageQualMatch)
+		})
+
+		r.Route("/bracket", func(r chi.Router) {
+			r.Use(basic.MultiAuthHandler())
+			r.Get("/", x.apiGetBracket)
+			r.Post("/generate", x.apiGenerateBracket)
+			r.Post("/{id}/result", x.apiBracketResult)
+			r.Post("/{id}/stage", x.apiStageBracketMatch)
+		})
+
+		r.Route("/inspection", func(r chi.Router) {
+			r.Use(basic.MultiAuthHandler())
+			r.Get("/", x.apiGetInspections)
+			r.Get("/{team}", x.apiGetTeamInspections)
+			r.Post("/{team}", x.apiInspectTeam)
+		})
+
+		r.Route("/practice", func(r chi.Router) {
+			r.Use(basic.MultiAuthHandler())
+			r.Get("/", x.apiGetPracticeBookings)
+			r.Post("/", x.apiBookPractice)
+			r.Delete("/{id}", x.apiCancelPractice)
+		})
+
+		r.Route("/scoring", func(r chi.Router) {
+			r.Use(x.requireScoring)
+			r.Get("/results", x.apiGetScoreResults)
+			r.Get("/rankings", x.apiGetScoreRankings)
+			r.With(basic.MultiAuthHandler()).Post("/matches/{id}", x.apiUpdateScore)
+		})
+
+		r.Route("/portal", func(r chi.Router) {
+			r.Use(x.requirePortal)
+			r.Get("/", x.apiGetPortal)
+			r.Get("/matches/{id}/telemetry", x.apiGetPortalMatchTelemetry)
+			r.Post("/practice", x.apiPortalBookPractice)
+		})
+
+		r.Route("/teams", func(r chi.Router) {
+			r.Get("/{id}/status", x.apiGetTeamStatus)
+			r.Get("/{id}/telemetry", x.apiGetTeamTelemetry)
 		})
 	})
 
@@ -154,12 +268,33 @@ func New(opts ...Option) (*FMS, error) {
 		r.Get("/", x.uiViewLanding)
 		r.Route("/display", func(r chi.Router) {
 			r.Get("/field-hud", x.uiViewFieldHUD)
+			r.Get("/practice-board", x.uiViewPracticeBoard)
+		})
+		r.Route("/team", func(r chi.Router) {
+			r.Get("/", x.uiViewTeamList)
+			r.Get("/{number}", x.uiViewTeamStatus)
+			r.Get("/{number}/qr.png", x.uiViewTeamQR)
+		})
+		r.Route("/portal", func(r chi.Router) {
+			r.Use(x.requirePortal)
+			r.Get("/", x.uiViewPortal)
+			r.Get("/wifi.png", x.uiViewPortalWifiQR)
+		})
+		r.Route("/scoring", func(r chi.Router) {
+			r.Use(x.requireScoring)
+			r.Get("/results", x.uiViewScoreResults)
+			r.Get("/rankings", x.uiViewScoreRankings)
 		})
 
 		r.Route("/admin", func(r chi.Router) {
 			r.Use(basic.LoginHandler("/login"))
 			r.Get("/", x.uiViewAdminLanding)
 			r.Get("/bind", x.uiViewAdminBind)
+			r.Get("/alerts", x.uiViewAlerts)
+			r.Get("/matches", x.uiViewMatchList)
+			r.Get("/schedule", x.uiViewQualSchedule)
+			r.Get("/bracket", x.uiViewBracket)
+			r.Get("/practice", x.uiViewPracticeBookings)
 
 			r.Route("/map", func(r chi.Router) {
 				r.Get("/current", x.uiViewCurrentMap)
@@ -178,14 +313,32 @@ func New(opts ...Option) (*FMS, error) {
 				r.Get("/flash-device", x.uiViewFlashDevice)
 				r.Get("/bootstrap-net", x.uiViewBootstrapNet)
 				r.Get("/compat-check", x.uiViewCompatCheck)
+				r.Get("/alerts", x.uiViewAlertThresholds)
+				r.Get("/scoring", x.uiViewScoringSetup)
+				r.Get("/inspection", x.uiViewInspectionSetup)
+				r.Get("/portal", x.uiViewPortalSetup)
+			})
+
+			r.Route("/scoring", func(r chi.Router) {
+				r.Use(x.requireScoring)
+				r.Get("/", x.uiViewScoreList)
+				r.Get("/{id}", x.uiViewScoreEntry)
+			})
+
+			r.Route("/inspection", func(r chi.Router) {
+				r.Get("/", x.uiViewInspectionList)
+				r.Get("/{team}", x.uiViewInspectionEntry)
 			})
 
 			r.Route("/net", func(r chi.Router) {
 				r.Get("/reconcile", x.uiViewNetReconcile)
 			})
+
+			r.Route("/integrations", x.mountIntegrationUI)
 		})
 	})
 
+	r.Get("/metrics", x.promMetrics().ServeHTTP)
 	r.Get("/metrics-sd", x.promSD)
 	r.Handle("/", nhttp.RedirectHandler("/ui/", nhttp.StatusMovedPermanently))
 	r.Handle("/docs/*", docs.MakeHandler("/docs"))
@@ -197,6 +350,14 @@ func New(opts ...Option) (*FMS, error) {
 // Serve commences serving of the FMS endpoints.
 func (f *FMS) Serve(bind string) error {
 	go f.doConnectedUpkeep()
+	go f.doAlertUpkeep()
+	go f.doAutoMapUpkeep()
+	go f.doPracticeUpkeep()
+	for _, i := range integrations {
+		if i.Run != nil {
+			go i.Run(f)
+		}
+	}
 	go f.gizmoUDPServelet()
 	f.swg.Done()
 
@@ -205,7 +366,7 @@ func (f *FMS) Serve(bind string) error {
 
 // Shutdown stops all components of the FMS.
 func (f *FMS) Shutdown(ctx context.Context) error {
-	f.stop <- struct{}{}
+	close(f.stop)
 	return f.s.Shutdown(ctx)
 }
 
@@ -218,6 +379,9 @@ func (f *FMS) doTemplate(w nhttp.ResponseWriter, r *nhttp.Request, tmpl string,
 		ctx = pongo2.Context{"shownav": true}
 	}
 	ctx["user"], _ = r.Context().Value(authware.UserKey{}).(authware.User)
+	if team, ok := f.portalTeam(r); ok {
+		ctx["portalTeam"] = team
+	}
 	t, err := f.tpl.FromCache(tmpl)
 	if err != nil {
 		f.templateErrorHandler(w, err)
@@ -13,6 +13,7 @@ import (
 
 	"github.com/gizmo-platform/gizmo/pkg/config"
 	"github.com/gizmo-platform/gizmo/pkg/ds"
+	rconfig "github.com/gizmo-platform/gizmo/pkg/routeros/config"
 )
 
 func (f *FMS) doConnectedUpkeep() {
@@ -24,6 +25,7 @@ func (f *FMS) doConnectedUpkeep() {
 			ticker.Stop()
 			return
 		case <-ticker.C:
+			gone := []webhookTeam{}
 			f.connectedMutex.Lock()
 			f.metaMutex.Lock()
 			for id, expiry := range f.connectedDS {
@@ -31,16 +33,22 @@ func (f *FMS) doConnectedUpkeep() {
 
 					delete(f.connectedDS, id)
 					delete(f.dsMeta, id)
+					gone = append(gone, webhookTeam{id, deviceDS})
 				}
 			}
 			for id, expiry := range f.connectedGizmo {
 				if time.Now().After(expiry) {
 					delete(f.connectedGizmo, id)
 					delete(f.gizmoMeta, id)
+					gone = append(gone, webhookTeam{id, deviceGizmo})
 				}
 			}
 			f.metaMutex.Unlock()
 			f.connectedMutex.Unlock()
+			for _, t := range gone {
+				f.logConnection(t.Team, t.Device, false)
+				f.notifyWebhooks(webhookTeamDisconnected, t)
+			}
 
 			f.dsPresentMutex.Lock()
 			for _, quad := range f.quads {
@@ -48,15 +56,41 @@ func (f *FMS) doConnectedUpkeep() {
 				t, err := f.tlm.GetActualDS(quad)
 				if err != nil {
 					f.l.Trace("Error pulling from field", "error", err)
+					if !errors.Is(err, rconfig.ErrNoNeighbor) {
+						f.metrics.actualDSErrors.WithLabelValues(quadField(quad)).Inc()
+					}
 					continue
 				}
 				f.dsPresent[quad] = t
 			}
 			f.dsPresentMutex.Unlock()
+
+			f.expireRobotTelemetry()
+			f.expireTelemetryHistory()
+			f.updateConnectedMetrics()
 		}
 	}
 }
 
+// markConnected notes that a device has reported in, and notifies
+// webhooks if it wasn't already connected.
+func (f *FMS) markConnected(device string, team int) {
+	connected := f.connectedDS
+	if device == deviceGizmo {
+		connected = f.connectedGizmo
+	}
+
+	f.connectedMutex.Lock()
+	_, was := connected[team]
+	connected[team] = time.Now().Add(time.Second * 5)
+	f.connectedMutex.Unlock()
+
+	if !was {
+		f.logConnection(team, device, true)
+		f.notifyWebhooks(webhookTeamConnected, webhookTeam{team, device})
+	}
+}
+
 func (f *FMS) gizmoConfig(w http.ResponseWriter, r *http.Request) {
 	tStr := chi.URLParam(r, "id")
 	team, err := strconv.Atoi(tStr)
@@ -102,9 +136,7 @@ func (f *FMS) gizmoDSMetaReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	f.connectedMutex.Lock()
-	f.connectedDS[team] = time.Now().Add(time.Second * 5)
-	f.connectedMutex.Unlock()
+	f.markConnected(deviceDS, team)
 
 	f.metaMutex.Lock()
 	f.dsMeta[team] = d
@@ -127,15 +159,32 @@ func (f *FMS) gizmoMetaReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	f.connectedMutex.Lock()
-	f.connectedGizmo[team] = time.Now().Add(time.Second * 5)
-	f.connectedMutex.Unlock()
+	f.markConnected(deviceGizmo, team)
 
 	f.metaMutex.Lock()
 	f.gizmoMeta[team] = d
 	f.metaMutex.Unlock()
 }
 
+// gizmoOfflineReport is sent by a driver's station when its Gizmo has
+// stopped reporting for longer than the driver's station's TTL.
+func (f *FMS) gizmoOfflineReport(w http.ResponseWriter, r *http.Request) {
+	team, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		f.l.Warn("Bad Gizmo Offline Report", "error", err)
+		return
+	}
+
+	f.robotTelemetryMutex.Lock()
+	delete(f.robotTelemetry, team)
+	f.robotTelemetryMutex.Unlock()
+
+	f.l.Info("Robot is offline", "team", team)
+	f.es.PublishRobotState(team, robotStateOffline)
+	f.notifyWebhooks(webhookRobotOffline, webhookTeam{team, deviceGizmo})
+}
+
 func (f *FMS) gizmoUDPServelet() error {
 	l := f.l.Named("udp")
 	conn, err := net.ListenUDP("udp", &net.UDPAddr{
@@ -180,9 +229,7 @@ func (f *FMS) gizmoUDPServelet() error {
 				continue
 			}
 
-			f.connectedMutex.Lock()
-			f.connectedGizmo[team] = time.Now().Add(time.Second * 5)
-			f.connectedMutex.Unlock()
+			f.markConnected(deviceGizmo, team)
 
 			f.metaMutex.Lock()
 			f.gizmoMeta[team] = d
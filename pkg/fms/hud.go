@@ -0,0 +1,117 @@
+package fms
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+)
+
+// hudQuad is everything the heads up display shows about a single
+// quadrant.
+type hudQuad struct {
+	Color           string
+	Actual          int
+	Team            int
+	GizmoConnected  bool
+	GizmoFirmwareOK bool
+	GizmoHardwareOK bool
+	GizmoMeta       config.GizmoMeta
+	DSConnected     bool
+	DSBootOK        bool
+	DSVersionOK     bool
+	DSMeta          config.DSMeta
+	DSProblems      []string
+	Alerts          []alert
+
+	// Battery and RSSI are the recent history for drawing
+	// sparklines.
+	Battery []float64
+	RSSI    []float64
+
+	// ControlLoss and ControlRTT are the most recent link
+	// statistics for the robot's control frames.
+	HasLink     bool
+	ControlLoss float64
+	ControlRTT  float64
+}
+
+// hudModel assembles the state of every quadrant on every field,
+// grouped by field in order of field number.  Field numbers need not
+// be contiguous, since fields can be removed.
+func (f *FMS) hudModel() [][]hudQuad {
+	f.dsPresentMutex.RLock()
+	defer f.dsPresentMutex.RUnlock()
+	m, _ := f.tlm.GetCurrentMapping()
+	tm := f.invertTLMMap(m)
+
+	byField := make(map[int][]hudQuad)
+	for _, field := range f.quads {
+		parts := strings.Split(field, ":")
+		if len(parts) != 2 {
+			f.l.Error("Bad quad name", "quad", field)
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(parts[0], "field"))
+		if err != nil {
+			f.l.Error("Error decoding field number", "error", err)
+			continue
+		}
+		team := tm[field]
+
+		fTmp := hudQuad{
+			Color:  parts[1],
+			Team:   team,
+			Actual: f.dsPresent[field],
+		}
+		f.connectedMutex.RLock()
+		_, fTmp.GizmoConnected = f.connectedGizmo[team]
+		_, fTmp.DSConnected = f.connectedDS[team]
+		f.connectedMutex.RUnlock()
+
+		f.metaMutex.RLock()
+		fTmp.GizmoMeta = f.gizmoMeta[team]
+		fTmp.GizmoHardwareOK = fTmp.GizmoMeta.HWVersionOK(f.c.CompatHardwareVersions)
+		fTmp.GizmoFirmwareOK = fTmp.GizmoMeta.FWVersionOK(f.c.CompatFirmwareVersions)
+		fTmp.DSMeta = f.dsMeta[team]
+		fTmp.DSVersionOK = fTmp.DSMeta.VersionOK(f.c.CompatDSVersions)
+		fTmp.DSBootOK = fTmp.DSMeta.BootmodeOK(f.c.CompatDSBootmodes)
+		f.metaMutex.RUnlock()
+		if fTmp.DSConnected {
+			fTmp.DSProblems = fTmp.DSMeta.Health.Problems()
+		}
+
+		if team != 0 {
+			fTmp.Alerts = f.alertsForTeam(team)
+
+			samples, _ := f.recentTelemetry(team, hudTelemetryWindow)
+			fTmp.Battery = make([]float64, len(samples))
+			fTmp.RSSI = make([]float64, len(samples))
+			for i, s := range samples {
+				fTmp.Battery[i] = s.BatteryVoltage
+				fTmp.RSSI[i] = s.RSSI
+			}
+			if len(samples) > 0 {
+				latest := samples[len(samples)-1]
+				fTmp.HasLink = true
+				fTmp.ControlLoss = latest.ControlLoss
+				fTmp.ControlRTT = latest.ControlRTT
+			}
+		}
+
+		byField[n] = append(byField[n], fTmp)
+	}
+
+	fields := make([]int, 0, len(byField))
+	for n := range byField {
+		fields = append(fields, n)
+	}
+	sort.Ints(fields)
+
+	out := make([][]hudQuad, 0, len(fields))
+	for _, n := range fields {
+		out = append(out, byField[n])
+	}
+	return out
+}
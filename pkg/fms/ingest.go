@@ -0,0 +1,271 @@
+package fms
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/gizmo-platform/gizmo/pkg/metrics"
+)
+
+// Driver's stations forward the status reports from their Gizmo to
+// the FMS in batches.  The driver's station's clock can't be trusted,
+// so sample times are corrected to the FMS's clock on arrival.  Only
+// the most recent report for each team is kept, and it is exported
+// through the FMS's own registry so that robot telemetry is still
+// visible even if Prometheus can't reach the driver's station
+// directly.
+
+const (
+	// robotTelemetryTTL is how long a report is kept after it is
+	// received.  This keeps robots that have been turned off from
+	// lingering with stale values.
+	robotTelemetryTTL = time.Second * 10
+
+	// robotStateOffline is published when a driver's station says
+	// that its robot has stopped reporting.
+	robotStateOffline = "offline"
+)
+
+var robotLabels = []string{"team", "field", "quadrant"}
+
+// robotMetrics maps the metrics that are exported to how they are
+// obtained from a report.  These use the same names as the metrics
+// that the driver's station exports.
+var robotMetrics = []struct {
+	desc  *prometheus.Desc
+	value func(metrics.Report) float64
+}{
+	{
+		prometheus.NewDesc("gizmo_robot_rssi", "WiFi signal strength as measured by the system processor.", robotLabels, nil),
+		func(r metrics.Report) float64 { return float64(r.RSSI) },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_wifi_reconnects", "Number of reconnects since last boot", robotLabels, nil),
+		func(r metrics.Report) float64 { return float64(r.WifiReconnects) },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_battery_voltage", "Robot Battery volage.", robotLabels, nil),
+		func(r metrics.Report) float64 { return r.Voltage() },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_power_board", "General logic power available.", robotLabels, nil),
+		func(r metrics.Report) float64 { return boolToFloat(r.PwrBoard) },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_power_pico", "Pico power supply available.", robotLabels, nil),
+		func(r metrics.Report) float64 { return boolToFloat(r.PwrPico) },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_power_gpio", "GPIO power supply available.", robotLabels, nil),
+		func(r metrics.Report) float64 { return boolToFloat(r.PwrGPIO) },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_power_servo", "Servo power available.", robotLabels, nil),
+		func(r metrics.Report) float64 { return boolToFloat(r.PwrServo) },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_power_bus_a", "Motor Bus A power available.", robotLabels, nil),
+		func(r metrics.Report) float64 { return boolToFloat(r.PwrMainA) },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_power_bus_b", "Motor Bus B power available.", robotLabels, nil),
+		func(r metrics.Report) float64 { return boolToFloat(r.PwrMainB) },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_power_pixels", "Student Pixel power available.", robotLabels, nil),
+		func(r metrics.Report) float64 { return boolToFloat(r.PwrPixels) },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_watchdog_ok", "Watchdog has been fed and is alive.", robotLabels, nil),
+		func(r metrics.Report) float64 { return boolToFloat(r.WatchdogOK) },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_watchdog_remaining_seconds", "Watchdog lifetime remaining since last feed.", robotLabels, nil),
+		func(r metrics.Report) float64 { return float64(r.WatchdogRemaining) / 1000 },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_control_frames", "Count of control frames received since power on.", robotLabels, nil),
+		func(r metrics.Report) float64 { return float64(r.ControlFramesReceived) },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_control_frame_age_seconds", "Time since last control frame was received", robotLabels, nil),
+		func(r metrics.Report) float64 { return float64(r.ControlFrameAge) / 1000 },
+	},
+}
+
+// robotLinkMetrics are computed by the driver's station rather than
+// reported by the Gizmo.
+var robotLinkMetrics = []struct {
+	desc  *prometheus.Desc
+	value func(metrics.LinkStats) float64
+}{
+	{
+		prometheus.NewDesc("gizmo_robot_control_loss_ratio", "Fraction of control frames lost between status reports.", robotLabels, nil),
+		func(l metrics.LinkStats) float64 { return l.Loss },
+	},
+	{
+		prometheus.NewDesc("gizmo_robot_control_rtt_seconds", "Round trip time of control frames as measured by the driver's station.", robotLabels, nil),
+		func(l metrics.LinkStats) float64 { return l.RTT },
+	},
+}
+
+var robotLastReportDesc = prometheus.NewDesc(
+	"gizmo_robot_last_report_timestamp_seconds",
+	"Time that the FMS last received a report from the robot.",
+	robotLabels, nil,
+)
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (f *FMS) gizmoDSTelemetryReport(w http.ResponseWriter, r *http.Request) {
+	team, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		f.l.Warn("Bad telemetry report", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	batch := []metrics.Sample{}
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		f.l.Warn("Error deserializing telemetry report", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if len(batch) == 0 {
+		return
+	}
+
+	sort.Slice(batch, func(i, j int) bool {
+		return batch[i].Time.Before(batch[j].Time)
+	})
+	correctSampleTimes(batch, r.Header.Get(metrics.SentHeader), time.Now())
+	latest := batch[len(batch)-1]
+
+	f.robotTelemetryMutex.Lock()
+	if existing, ok := f.robotTelemetry[team]; !ok || latest.Time.After(existing.Time) {
+		f.robotTelemetry[team] = latest
+	}
+	for _, s := range batch {
+		f.recordTelemetry(team, s)
+	}
+	f.robotTelemetryMutex.Unlock()
+
+	f.archiveTelemetry(team, batch)
+}
+
+// correctSampleTimes moves the samples from the driver's station's
+// clock onto the FMS's, since everything that ages out telemetry or
+// matches it up with a match uses the FMS's clock.  The samples keep
+// their spacing.  A driver's station that doesn't say when it sent
+// the batch is assumed to have sent it as soon as the newest sample
+// was taken.  The batch must be sorted.
+func correctSampleTimes(batch []metrics.Sample, sent string, received time.Time) {
+	offset := received.Sub(batch[len(batch)-1].Time)
+	if t, err := time.Parse(time.RFC3339Nano, sent); err == nil {
+		offset = received.Sub(t)
+	}
+	for i := range batch {
+		batch[i].Time = batch[i].Time.Add(offset)
+		if batch[i].Time.After(received) {
+			batch[i].Time = received
+		}
+	}
+}
+
+// expireRobotTelemetry removes reports that are older than the TTL.
+func (f *FMS) expireRobotTelemetry() {
+	f.robotTelemetryMutex.Lock()
+	for team, s := range f.robotTelemetry {
+		if time.Since(s.Time) > robotTelemetryTTL {
+			delete(f.robotTelemetry, team)
+		}
+	}
+	f.robotTelemetryMutex.Unlock()
+}
+
+// robotCollector exports the latest robot telemetry.  The field and
+// quadrant are looked up at collection time so that they always
+// reflect the current mapping.
+type robotCollector struct {
+	f *FMS
+}
+
+func (c robotCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, m := range robotMetrics {
+		ch <- m.desc
+	}
+	for _, m := range robotLinkMetrics {
+		ch <- m.desc
+	}
+	ch <- robotLastReportDesc
+}
+
+func (c robotCollector) Collect(ch chan<- prometheus.Metric) {
+	mapping, _ := c.f.tlm.GetCurrentMapping()
+
+	c.f.robotTelemetryMutex.RLock()
+	defer c.f.robotTelemetryMutex.RUnlock()
+
+	for team, s := range c.f.robotTelemetry {
+		field, quadrant := "none", "none"
+		if parts := strings.SplitN(mapping[team], ":", 2); len(parts) == 2 {
+			field, quadrant = parts[0], parts[1]
+		}
+		labels := []string{strconv.Itoa(team), field, quadrant}
+
+		for _, m := range robotMetrics {
+			ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, m.value(s.Report), labels...)
+		}
+		for _, m := range robotLinkMetrics {
+			ch <- prometheus.MustNewConstMetric(m.desc, prometheus.GaugeValue, m.value(s.Link), labels...)
+		}
+		ch <- prometheus.MustNewConstMetric(robotLastReportDesc, prometheus.GaugeValue, float64(s.Time.Unix()), labels...)
+	}
+}
+
+// robotExtraCollector exports the extra values that newer firmware
+// sends outside of the fixed report.  The set of metrics isn't known
+// in advance, so this is an unchecked collector and describes
+// nothing.
+type robotExtraCollector struct {
+	f *FMS
+}
+
+func (c robotExtraCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+func (c robotExtraCollector) Collect(ch chan<- prometheus.Metric) {
+	mapping, _ := c.f.tlm.GetCurrentMapping()
+
+	c.f.robotTelemetryMutex.RLock()
+	defer c.f.robotTelemetryMutex.RUnlock()
+
+	descs := make(map[string]*prometheus.Desc)
+	for team, s := range c.f.robotTelemetry {
+		field, quadrant := "none", "none"
+		if parts := strings.SplitN(mapping[team], ":", 2); len(parts) == 2 {
+			field, quadrant = parts[0], parts[1]
+		}
+		labels := []string{strconv.Itoa(team), field, quadrant}
+
+		for metric, val := range s.Report.ExtraMetrics(metrics.DefaultExtraAllowList) {
+			desc, ok := descs[metric]
+			if !ok {
+				desc = prometheus.NewDesc(metric, "Value reported by the robot outside the fixed report.", robotLabels, nil)
+				descs[metric] = desc
+			}
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, val, labels...)
+		}
+	}
+}
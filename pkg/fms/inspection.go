@@ -0,0 +1,245 @@
+package fms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+	"github.com/gizmo-platform/gizmo/pkg/inspection"
+)
+
+// Robots are inspected before they compete.  The inspector works
+// through the checklist from the config, and the versions the team's
+// Gizmo last reported are saved with the result as evidence.  The
+// inspection policy can then warn about or refuse mappings that put a
+// team on a competition field before they have passed.
+
+// errNotInspected is returned when a mapping is refused because it
+// includes teams that haven't passed inspection.
+var errNotInspected = errors.New("teams have not passed inspection")
+
+// inspectionRequest is the body of a request to record an inspection.
+type inspectionRequest struct {
+	Inspector string
+	Passed    bool
+	Notes     string
+	Items     map[string]bool
+}
+
+// inspectionRow is a team as it is shown on the inspection list.
+type inspectionRow struct {
+	Team      *config.Team
+	Inspected bool
+	Record    inspection.Record
+}
+
+// uninspected returns the teams in a mapping that haven't passed
+// inspection.  Teams that are already mapped to the same quad in the
+// current mapping were let on before and aren't checked again, and
+// neither are teams on fields that map automatically.
+func (f *FMS) uninspected(m, current map[int]string) []int {
+	out := []int{}
+	for team, quad := range m {
+		if current[team] == quad || f.quadIsAutoMapped(quad) {
+			continue
+		}
+		if !f.inspections.Passed(team) {
+			out = append(out, team)
+		}
+	}
+	sort.Ints(out)
+	return out
+}
+
+// checkInspections applies the inspection policy to a mapping.  Under
+// the warn policy the mapping is allowed but the operators are told
+// about it, and under the reject policy it is refused.
+func (f *FMS) checkInspections(m, current map[int]string) error {
+	if f.c.InspectionPolicy != "WARN" && f.c.InspectionPolicy != "REJECT" {
+		return nil
+	}
+
+	teams := f.uninspected(m, current)
+	if len(teams) == 0 {
+		return nil
+	}
+	strs := make([]string, len(teams))
+	for i, team := range teams {
+		strs[i] = strconv.Itoa(team)
+	}
+	err := fmt.Errorf("%w: %s", errNotInspected, strings.Join(strs, ", "))
+
+	if f.c.InspectionPolicy == "REJECT" {
+		return err
+	}
+	f.l.Warn("Mapping teams that have not passed inspection", "teams", teams)
+	f.es.PublishError(err)
+	return nil
+}
+
+func (f *FMS) apiGetInspections(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(f.inspections.Latest())
+}
+
+func (f *FMS) apiGetTeamInspections(w http.ResponseWriter, r *http.Request) {
+	team, err := strconv.Atoi(chi.URLParam(r, "team"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(f.inspections.History(team))
+}
+
+func (f *FMS) apiInspectTeam(w http.ResponseWriter, r *http.Request) {
+	team, err := strconv.Atoi(chi.URLParam(r, "team"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := f.c.Teams[team]; !ok {
+		http.Error(w, fmt.Sprintf("team %d is not on the roster", team), http.StatusNotFound)
+		return
+	}
+
+	req := new(inspectionRequest)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Inspector) == "" {
+		http.Error(w, "the inspector must be named", http.StatusBadRequest)
+		return
+	}
+
+	// Only items on the checklist are kept, and a robot can't
+	// pass without all of them.
+	items := make(map[string]bool, len(f.c.InspectionItems))
+	for _, item := range f.c.InspectionItems {
+		items[item.Key] = req.Items[item.Key]
+		if req.Passed && !items[item.Key] {
+			http.Error(w, fmt.Sprintf("a robot can't pass without %s", item.Name), http.StatusBadRequest)
+			return
+		}
+	}
+
+	f.metaMutex.RLock()
+	meta := f.gizmoMeta[team]
+	f.metaMutex.RUnlock()
+
+	rec := inspection.Record{
+		Team:            team,
+		Inspector:       req.Inspector,
+		Passed:          req.Passed,
+		Notes:           req.Notes,
+		Items:           items,
+		HardwareVersion: meta.HardwareVersion,
+		FirmwareVersion: meta.FirmwareVersion,
+	}
+	if err := f.inspections.Add(rec); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+	f.es.PublishActionComplete("Inspection Save")
+}
+
+func (f *FMS) apiUpdateInspection(w http.ResponseWriter, r *http.Request) {
+	cTmp := new(config.FMSConfig)
+
+	if err := json.NewDecoder(r.Body).Decode(&cTmp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch cTmp.InspectionPolicy {
+	case "OFF", "WARN", "REJECT":
+	default:
+		http.Error(w, "the inspection policy must be OFF, WARN, or REJECT", http.StatusBadRequest)
+		return
+	}
+	seen := make(map[string]bool, len(cTmp.InspectionItems))
+	for _, item := range cTmp.InspectionItems {
+		if item.Key == "" || item.Name == "" {
+			http.Error(w, "inspection items must have a key and a name", http.StatusBadRequest)
+			return
+		}
+		if seen[item.Key] {
+			http.Error(w, "duplicate inspection item: "+item.Key, http.StatusBadRequest)
+			return
+		}
+		seen[item.Key] = true
+	}
+
+	// We do this rather than deserializing into the main config
+	// struct to ensure that its not possible to rewrite other
+	// unrelated parts of the config via this API.
+	f.c.InspectionItems = cTmp.InspectionItems
+	f.c.InspectionPolicy = cTmp.InspectionPolicy
+
+	if err := f.c.Save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+	f.es.PublishActionComplete("Configuration Save")
+}
+
+func (f *FMS) uiViewInspectionSetup(w http.ResponseWriter, r *http.Request) {
+	f.doTemplate(w, r, "views/setup/inspection.p2", pongo2.Context{"cfg": f.c})
+}
+
+func (f *FMS) uiViewInspectionList(w http.ResponseWriter, r *http.Request) {
+	latest := f.inspections.Latest()
+
+	rows := []inspectionRow{}
+	for _, t := range f.c.SortedTeams() {
+		rec, ok := latest[t.Number]
+		rows = append(rows, inspectionRow{Team: t, Inspected: ok, Record: rec})
+	}
+	f.doTemplate(w, r, "views/inspection/list.p2", pongo2.Context{"rows": rows, "cfg": f.c})
+}
+
+func (f *FMS) uiViewInspectionEntry(w http.ResponseWriter, r *http.Request) {
+	team, err := strconv.Atoi(chi.URLParam(r, "team"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		f.doTemplate(w, r, "errors/internal.p2", pongo2.Context{"error": err})
+		return
+	}
+	t, ok := f.c.Teams[team]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		f.doTemplate(w, r, "errors/internal.p2", pongo2.Context{"error": fmt.Errorf("team %d is not on the roster", team)})
+		return
+	}
+
+	history := f.inspections.History(team)
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+
+	f.metaMutex.RLock()
+	meta := f.gizmoMeta[team]
+	f.metaMutex.RUnlock()
+
+	ctx := pongo2.Context{
+		"team":       t,
+		"history":    history,
+		"items":      f.c.InspectionItems,
+		"meta":       meta,
+		"hardwareOK": meta.HWVersionOK(f.c.CompatHardwareVersions),
+		"firmwareOK": meta.FWVersionOK(f.c.CompatFirmwareVersions),
+	}
+	if len(history) > 0 {
+		ctx["last"] = history[0]
+	}
+	f.doTemplate(w, r, "views/inspection/entry.p2", ctx)
+}
@@ -0,0 +1,174 @@
+package fms
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+)
+
+// Integrations connect the FMS to other systems.  Each one registers
+// itself from init() with everything the FMS needs to run it, so that
+// adding an integration doesn't require changes anywhere else.  Its
+// settings are stored in the config and edited from the integrations
+// setup page.
+
+// integration is an integration along with the hooks that run it.
+// All hooks are optional.
+type integration struct {
+	config.IntegrationInfo
+
+	// Init is called while the FMS is being created, and is where
+	// any state the integration needs should be set up.
+	Init func(*FMS)
+
+	// Run is called in its own goroutine when the FMS starts
+	// serving, and must return once the FMS is stopped.  It runs
+	// whether or not the integration is enabled.
+	Run func(*FMS)
+
+	// Public routes are mounted at the root of the router and do
+	// not require credentials, since scoring systems generally
+	// can't log in.  They are only reachable while the
+	// integration is enabled.
+	Public func(*FMS, chi.Router)
+
+	// API routes are mounted at /api/integrations/<slug> and UI
+	// routes at /ui/admin/integrations/<slug>.  Both require
+	// credentials, and are reachable even if the integration is
+	// disabled so that it can be set up first.
+	API func(*FMS, chi.Router)
+	UI  func(*FMS, chi.Router)
+}
+
+// integrationView is what the setup page shows for an integration.
+// Passwords are never sent back to the page, Stored only says whether
+// one has been set.
+type integrationView struct {
+	config.IntegrationInfo
+
+	Enabled bool
+	Values  map[string]string
+	Stored  map[string]bool
+	Page    string
+}
+
+var integrations = []*integration{}
+
+func registerIntegration(i *integration) {
+	config.RegisterIntegration(i.IntegrationInfo)
+	integrations = append(integrations, i)
+	sort.Slice(integrations, func(a, b int) bool {
+		return integrations[a].ID < integrations[b].ID
+	})
+}
+
+func integrationBySlug(slug string) (*integration, bool) {
+	for _, i := range integrations {
+		if i.Slug == slug {
+			return i, true
+		}
+	}
+	return nil, false
+}
+
+// mountIntegrations mounts the public routes of every integration.
+// The API and UI routes are mounted by mountIntegrationAPI and
+// mountIntegrationUI inside of the authenticated parts of the router.
+func (f *FMS) mountIntegrations(r chi.Router) {
+	for _, i := range integrations {
+		if i.Public == nil {
+			continue
+		}
+		r.Group(func(r chi.Router) {
+			r.Use(f.requireIntegration(i.ID))
+			i.Public(f, r)
+		})
+	}
+}
+
+func (f *FMS) mountIntegrationAPI(r chi.Router) {
+	for _, i := range integrations {
+		if i.API == nil {
+			continue
+		}
+		r.Route("/"+i.Slug, func(r chi.Router) { i.API(f, r) })
+	}
+}
+
+func (f *FMS) mountIntegrationUI(r chi.Router) {
+	for _, i := range integrations {
+		if i.UI == nil {
+			continue
+		}
+		r.Route("/"+i.Slug, func(r chi.Router) { i.UI(f, r) })
+	}
+}
+
+// requireIntegration rejects requests while the integration is
+// disabled.
+func (f *FMS) requireIntegration(id config.Integration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !f.c.Integrations.Enabled(id) {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				w.Write([]byte("Integration is not enabled!"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (f *FMS) integrationViews() []integrationView {
+	out := make([]integrationView, len(integrations))
+	for idx, i := range integrations {
+		v := integrationView{
+			IntegrationInfo: i.IntegrationInfo,
+			Enabled:         f.c.Integrations.Enabled(i.ID),
+			Values:          make(map[string]string, len(i.Settings)),
+			Stored:          make(map[string]bool),
+		}
+		for _, s := range i.Settings {
+			if s.Type == config.SettingPassword {
+				v.Stored[s.Key] = f.c.IntegrationSetting(i.ID, s.Key) != ""
+				continue
+			}
+			v.Values[s.Key] = f.c.IntegrationSetting(i.ID, s.Key)
+		}
+		if i.UI != nil {
+			v.Page = "/ui/admin/integrations/" + i.Slug + "/"
+		}
+		out[idx] = v
+	}
+	return out
+}
+
+func (f *FMS) apiUpdateIntegrationSettings(w http.ResponseWriter, r *http.Request) {
+	i, ok := integrationBySlug(chi.URLParam(r, "slug"))
+	if !ok {
+		http.Error(w, config.ErrUnknownIntegration.Error(), http.StatusNotFound)
+		return
+	}
+
+	settings := make(map[string]string)
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := f.c.SetIntegrationSettings(i.ID, settings); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := f.c.Save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+	f.es.PublishActionComplete("Configuration Save")
+}
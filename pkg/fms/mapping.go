@@ -5,6 +5,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The kinds of mapping change, as they are recorded in the metrics.
+const (
+	mapKindImmediate  = "immediate"
+	mapKindStaged     = "staged"
+	mapKindAutomatic  = "automatic"
+	mapKindCorrection = "correction"
 )
 
 func (f *FMS) remapTeams(w http.ResponseWriter, r *http.Request) {
@@ -19,7 +31,7 @@ func (f *FMS) remapTeams(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := f.tlm.InsertOnDemandMap(mapping); err != nil {
+	if err := f.applyMapping(0, mapping); err != nil {
 		f.l.Error("Error remapping teams!", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprintf(w, "Error inserting map: %s", err)
@@ -29,26 +41,252 @@ func (f *FMS) remapTeams(w http.ResponseWriter, r *http.Request) {
 	f.l.Info("Immediately remapped teams!", "map", mapping)
 }
 
+// applyMapping immediately maps teams to fields and starts a new match
+// record for the mapping.  The number is the scheduled match number if
+// one is known, or zero otherwise.
+func (f *FMS) applyMapping(number int, m map[int]string) error {
+	f.mapMutex.Lock()
+	defer f.mapMutex.Unlock()
+
+	current, _ := f.tlm.GetCurrentMapping()
+	if err := f.insertMapping(mapKindImmediate, m, current); err != nil {
+		return err
+	}
+	f.beginMatch(number, "", m)
+	return nil
+}
+
+// updateMapping changes the current mapping in place.  The update is
+// called with a copy of the current mapping and returns false if
+// there is nothing to change.  The mapping lock is held throughout so
+// that changes made from different places at the same time aren't
+// lost.  This is for changes that don't begin a new match, such as
+// automap and practice on fields that aren't running matches, or a
+// team being moved to the quad they are really plugged into, so no
+// match record is started and the current one carries on.  The kind
+// is what the change is recorded as in the metrics.
+func (f *FMS) updateMapping(kind string, update func(map[int]string) bool) error {
+	f.mapMutex.Lock()
+	defer f.mapMutex.Unlock()
+
+	current, err := f.tlm.GetCurrentMapping()
+	if err != nil {
+		return err
+	}
+	m := make(map[int]string, len(current))
+	for team, quad := range current {
+		m[team] = quad
+	}
+	if !update(m) {
+		return nil
+	}
+
+	if err := f.insertMapping(kind, m, current); err != nil {
+		return err
+	}
+	f.notifyWebhooks(webhookMapCommitted, webhookMatch{Mapping: m})
+	return nil
+}
+
+// insertMapping checks and inserts a mapping.  The mapping lock must
+// be held.  Corrections that only move teams who are already on the
+// fields aren't held up by inspections, since they put the mapping
+// right rather than letting anyone new on.
+func (f *FMS) insertMapping(kind string, m, current map[int]string) error {
+	if kind != mapKindCorrection || !onlyMoves(m, current) {
+		if err := f.checkInspections(m, current); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	err := f.tlm.InsertOnDemandMap(m)
+	f.metrics.observeMapCommit(kind, start, err)
+	return err
+}
+
+// onlyMoves returns true if every team in the mapping is already
+// mapped in current.
+func onlyMoves(m, current map[int]string) bool {
+	for team := range m {
+		if _, ok := current[team]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// stagedMatch is what is known about a staged mapping that came from
+// a schedule, a scoring system, or the bracket.  Number is the
+// scheduled match number and Name is the name of a bracket match;
+// either may be unset.  Names are the team names the scoring system
+// sent, which may differ from the roster.
+type stagedMatch struct {
+	Number int
+	Name   string
+	Names  map[int]string
+}
+
+// stageMapping stages a mapping to be committed later.  The match is
+// the zero value if nothing is known about where the mapping came
+// from.  The mapping lock is held so that staging can't land part way
+// through a commit.
+func (f *FMS) stageMapping(match stagedMatch, m map[int]string) error {
+	f.mapMutex.Lock()
+	defer f.mapMutex.Unlock()
+
+	if err := f.checkInspections(m, nil); err != nil {
+		return err
+	}
+	if err := f.tlm.InsertStageMapping(m); err != nil {
+		return err
+	}
+
+	f.stagedMutex.Lock()
+	f.stagedMatch = nil
+	if match.Number != 0 || match.Name != "" {
+		f.stagedMatch = &match
+	}
+	f.stagedMutex.Unlock()
+	return nil
+}
+
+// staged returns what is known about the staged match, if anything.
+func (f *FMS) staged() (stagedMatch, bool) {
+	f.stagedMutex.RLock()
+	defer f.stagedMutex.RUnlock()
+	if f.stagedMatch == nil {
+		return stagedMatch{}, false
+	}
+	return *f.stagedMatch, true
+}
+
+// stagePending returns true if there is a stage mapping that hasn't
+// been committed.  A stage whose teams are all already where it puts
+// them has nothing left to do, even if it was never cleared.
+func (f *FMS) stagePending() bool {
+	stage, _ := f.tlm.GetStageMapping()
+	current, _ := f.tlm.GetCurrentMapping()
+	for team, quad := range stage {
+		if current[team] != quad {
+			return true
+		}
+	}
+	return false
+}
+
+// commitStagedMap applies the staged mapping, clears the stage, and
+// starts a new match record for it.  The stage is cleared with the
+// mapping lock still held so that a match staged by something else
+// in the meantime isn't lost.
+func (f *FMS) commitStagedMap() error {
+	f.mapMutex.Lock()
+	defer f.mapMutex.Unlock()
+
+	start := time.Now()
+	err := f.tlm.CommitStagedMap()
+	f.metrics.observeMapCommit(mapKindStaged, start, err)
+	if err != nil {
+		return err
+	}
+
+	f.stagedMutex.Lock()
+	staged := stagedMatch{}
+	if f.stagedMatch != nil {
+		staged = *f.stagedMatch
+	}
+	f.stagedMatch = nil
+	f.stagedMutex.Unlock()
+
+	m, _ := f.tlm.GetCurrentMapping()
+	f.beginMatch(staged.Number, staged.Name, m)
+
+	if err := f.tlm.InsertStageMapping(nil); err != nil {
+		return fmt.Errorf("mapping committed but the stage could not be cleared: %w", err)
+	}
+	return nil
+}
+
+func (f *FMS) beginMatch(number int, name string, m map[int]string) {
+	if err := f.archive.Begin(number, name, m); err != nil {
+		f.l.Warn("Could not start match record", "error", err)
+	}
+
+	wm := webhookMatch{Number: number, Name: name, Mapping: m}
+	if rec, err := f.archive.Current(); err == nil {
+		wm.ID = rec.ID
+	}
+	f.notifyWebhooks(webhookMapCommitted, wm)
+}
+
 func (f *FMS) currentTeamMap(w http.ResponseWriter, r *http.Request) {
 	enc := json.NewEncoder(w)
 	m, _ := f.tlm.GetCurrentMapping()
 	enc.Encode(m)
 }
 
+// promSD provides targets for every rostered team that is either
+// mapped to a field or has a driver's station talking to the FMS, so
+// that robots in the pits don't drop out of Grafana.  Each team is its
+// own target group so that it can carry labels describing where it
+// is.  There is no team label since the driver's station already puts
+// one on every robot metric.
 func (f *FMS) promSD(w http.ResponseWriter, r *http.Request) {
 	type promTarget struct {
-		Targets []string `json:"targets"`
+		Targets []string          `json:"targets"`
+		Labels  map[string]string `json:"labels"`
 	}
 
 	m, _ := f.tlm.GetCurrentMapping()
-	tgt := []string{}
+	number := 0
+	if rec, err := f.archive.Current(); err == nil {
+		number = rec.Number
+	}
+
+	f.connectedMutex.RLock()
+	teams := []int{}
+	for team := range f.c.Teams {
+		_, mapped := m[team]
+		_, connected := f.connectedDS[team]
+		if mapped || connected {
+			teams = append(teams, team)
+		}
+	}
+	f.connectedMutex.RUnlock()
+	sort.Ints(teams)
+
+	tgt := []promTarget{}
+	for _, team := range teams {
+		field, quadrant := "none", "none"
+		if parts := strings.SplitN(m[team], ":", 2); len(parts) == 2 {
+			field, quadrant = parts[0], parts[1]
+		}
+
+		f.metaMutex.RLock()
+		mode := f.dsMeta[team].Health.RadioMode
+		f.metaMutex.RUnlock()
+		if mode == "" {
+			mode = f.c.RadioMode
+		}
+
+		labels := map[string]string{
+			"team_name":  f.c.Teams[team].Name,
+			"field":      field,
+			"quadrant":   quadrant,
+			"radio_mode": mode,
+		}
+		if number != 0 && field != "none" {
+			labels["match"] = strconv.Itoa(number)
+		}
 
-	for id := range m {
-		tgt = append(tgt, fmt.Sprintf("10.%d.%d.2:8080", int(id/100), id%100))
+		tgt = append(tgt, promTarget{
+			Targets: []string{fmt.Sprintf("10.%d.%d.2:8080", int(team/100), team%100)},
+			Labels:  labels,
+		})
 	}
 
 	w.Header().Add("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode([]promTarget{{Targets: tgt}}); err != nil {
+	if err := json.NewEncoder(w).Encode(tgt); err != nil {
 		f.l.Warn("Error writing prom sd", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 	}
@@ -0,0 +1,154 @@
+package fms
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// fmsMetrics are the metrics that the FMS keeps about itself, as
+// opposed to the robot metrics which are scraped from the driver's
+// stations directly.
+type fmsMetrics struct {
+	dsConnected    *prometheus.GaugeVec
+	gizmoConnected *prometheus.GaugeVec
+	mapCommits     *prometheus.CounterVec
+	mapDuration    *prometheus.HistogramVec
+	actualDSErrors *prometheus.CounterVec
+	httpDuration   *prometheus.HistogramVec
+}
+
+func newFMSMetrics(r prometheus.Registerer) *fmsMetrics {
+	m := &fmsMetrics{
+		dsConnected: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gizmo",
+			Subsystem: "fms",
+			Name:      "ds_connected",
+			Help:      "Driver's stations currently checked in with the FMS.",
+		}, []string{"field"}),
+		gizmoConnected: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gizmo",
+			Subsystem: "fms",
+			Name:      "gizmo_connected",
+			Help:      "Gizmos currently checked in with the FMS.",
+		}, []string{"field"}),
+		mapCommits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gizmo",
+			Subsystem: "fms",
+			Name:      "map_commits_total",
+			Help:      "Mappings that have been committed to the field.",
+		}, []string{"kind", "result"}),
+		mapDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gizmo",
+			Subsystem: "fms",
+			Name:      "map_commit_duration_seconds",
+			Help:      "Time taken to commit a mapping to the field.",
+			Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"kind"}),
+		actualDSErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gizmo",
+			Subsystem: "fms",
+			Name:      "actual_ds_errors_total",
+			Help:      "Errors while polling fields for the driver's stations plugged into them.",
+		}, []string{"field"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gizmo",
+			Subsystem: "fms",
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "code"}),
+	}
+
+	r.MustRegister(
+		m.dsConnected,
+		m.gizmoConnected,
+		m.mapCommits,
+		m.mapDuration,
+		m.actualDSErrors,
+		m.httpDuration,
+	)
+	return m
+}
+
+// observeMapCommit records the outcome of committing a mapping.
+func (m *fmsMetrics) observeMapCommit(kind string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.mapCommits.WithLabelValues(kind, result).Inc()
+	m.mapDuration.WithLabelValues(kind).Observe(time.Since(start).Seconds())
+}
+
+// quadField returns the field portion of a quad, or "none" if the
+// quad is not in the expected form.
+func quadField(quad string) string {
+	parts := strings.SplitN(quad, ":", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "field") {
+		return "none"
+	}
+	return parts[0]
+}
+
+// updateConnectedMetrics counts the devices that are connected on
+// each field.
+func (f *FMS) updateConnectedMetrics() {
+	m, _ := f.tlm.GetCurrentMapping()
+
+	ds := make(map[string]float64)
+	gizmo := make(map[string]float64)
+	for _, quad := range f.quads {
+		ds[quadField(quad)] = 0
+		gizmo[quadField(quad)] = 0
+	}
+
+	f.connectedMutex.RLock()
+	for team := range f.connectedDS {
+		ds[quadField(m[team])]++
+	}
+	for team := range f.connectedGizmo {
+		gizmo[quadField(m[team])]++
+	}
+	f.connectedMutex.RUnlock()
+
+	f.metrics.dsConnected.Reset()
+	for field, count := range ds {
+		f.metrics.dsConnected.WithLabelValues(field).Set(count)
+	}
+	f.metrics.gizmoConnected.Reset()
+	for field, count := range gizmo {
+		f.metrics.gizmoConnected.WithLabelValues(field).Set(count)
+	}
+}
+
+// metricsMiddleware records the latency of every request against the
+// route pattern that served it rather than the literal path, so that
+// team numbers and IDs don't explode the number of series.
+func (f *FMS) metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		code := ww.Status()
+		if code == 0 {
+			code = http.StatusOK
+		}
+		f.metrics.httpDuration.WithLabelValues(r.Method, route, strconv.Itoa(code)).Observe(time.Since(start).Seconds())
+	})
+}
+
+func (f *FMS) promMetrics() http.Handler {
+	return promhttp.HandlerFor(f.promRegistry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,339 @@
+package fms
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+)
+
+// The MQTT bridge publishes the state of the FMS to a broker so that
+// scoreboards, stream overlays, and other event tooling can follow
+// along without polling the HTTP API.  All topics are relative to the
+// configured prefix, which defaults to "gizmo":
+//
+//	<prefix>/fms/status           "online" or "offline" (retained)
+//	<prefix>/field/hud            the heads up display model (retained)
+//	<prefix>/match/current        the current match record (retained)
+//	<prefix>/team/<n>/ds          DS connection state and metadata (retained)
+//	<prefix>/team/<n>/gizmo       Gizmo connection state and metadata (retained)
+//	<prefix>/team/<n>/telemetry   each telemetry sample as it arrives
+//	<prefix>/command/remap        remaps the field, if commands are accepted
+//	<prefix>/command/result       the outcome of each command
+//
+// All payloads other than the status are JSON.  The remap command
+// takes the same map of team numbers to quadrants as the
+// update-immediate API.  The broker is responsible for deciding who
+// may publish commands, so commands are only accepted when explicitly
+// enabled.
+//
+// The tests for the bridge need a broker and are skipped unless
+// GIZMO_TEST_MQTT_BROKER is set.  testdata/mqtt/compose.yml runs a
+// local mosquitto for them, and the tree can be watched with
+// `mosquitto_sub -v -t 'gizmo/#'`.
+
+const (
+	mqttRate     = time.Second
+	mqttClientID = "gizmo-fms"
+	mqttTimeout  = time.Second * 5
+
+	// mqttRemapQueue is how many remap commands may be waiting to
+	// be applied before more are refused.
+	mqttRemapQueue = 4
+)
+
+// mqttSettings are the parts of the configuration that require a new
+// connection when they change.
+type mqttSettings struct {
+	Broker         string
+	User           string
+	Pass           string
+	Prefix         string
+	AcceptCommands bool
+}
+
+type mqttBridge struct {
+	f   *FMS
+	l   hclog.Logger
+	c   mqtt.Client
+	cfg mqttSettings
+
+	// last holds the most recent payload published to each
+	// retained topic so that only changes are published.  It is
+	// discarded whenever resync is set.
+	last          map[string][]byte
+	lastTelemetry map[int]time.Time
+	resync        atomic.Bool
+
+	// remaps are applied outside of the client's message
+	// handler, since applying a mapping can take long enough to
+	// stall the client.
+	remaps chan map[int]string
+	done   chan struct{}
+}
+
+type mqttConnState struct {
+	Connected bool
+	Meta      interface{}
+}
+
+type mqttMatch struct {
+	ID      int
+	Number  int
+	Mapping map[int]string
+	Mapped  time.Time
+	Started time.Time
+	Stopped time.Time
+	Running bool
+}
+
+type mqttCommandResult struct {
+	Command string
+	OK      bool
+	Error   string `json:",omitempty"`
+}
+
+func init() {
+	registerIntegration(&integration{
+		IntegrationInfo: config.IntegrationInfo{
+			ID:          config.IntegrationMQTT,
+			Slug:        "mqtt",
+			Name:        "MQTT Bridge",
+			Description: "Publishes telemetry, connection state, the heads up display, and the current match to a broker under the topic prefix.  If commands are accepted, anything that can publish to <prefix>/command/remap on the broker can remap the fields, so only enable this if the broker restricts who may publish.",
+			Settings: []config.IntegrationSetting{
+				{Key: "broker", Label: "Broker", Type: config.SettingText, Default: "tcp://127.0.0.1:1883", Required: true},
+				{Key: "user", Label: "Username", Type: config.SettingText},
+				{Key: "pass", Label: "Password", Type: config.SettingPassword},
+				{Key: "prefix", Label: "Topic Prefix", Type: config.SettingText, Default: "gizmo", Required: true},
+				{Key: "commands", Label: "Accept Commands", Type: config.SettingBool, Default: "false"},
+			},
+		},
+		Run: (*FMS).doMQTTUpkeep,
+	})
+}
+
+func (f *FMS) mqttSettings() mqttSettings {
+	return mqttSettings{
+		Broker:         f.c.IntegrationSetting(config.IntegrationMQTT, "broker"),
+		User:           f.c.IntegrationSetting(config.IntegrationMQTT, "user"),
+		Pass:           f.c.IntegrationSetting(config.IntegrationMQTT, "pass"),
+		Prefix:         f.c.IntegrationSetting(config.IntegrationMQTT, "prefix"),
+		AcceptCommands: f.c.IntegrationSettingBool(config.IntegrationMQTT, "commands"),
+	}
+}
+
+// doMQTTUpkeep runs the bridge while the integration is enabled, and
+// reconnects it if its settings change.
+func (f *FMS) doMQTTUpkeep() {
+	ticker := time.NewTicker(mqttRate)
+	var b *mqttBridge
+
+	for {
+		select {
+		case <-f.stop:
+			ticker.Stop()
+			if b != nil {
+				b.close()
+			}
+			return
+		case <-ticker.C:
+			enabled := f.c.Integrations.Enabled(config.IntegrationMQTT)
+			if b != nil && (!enabled || b.cfg != f.mqttSettings()) {
+				b.close()
+				b = nil
+			}
+			if !enabled {
+				continue
+			}
+			if b == nil {
+				b = f.newMQTTBridge(f.mqttSettings())
+			}
+			if b.c.IsConnected() {
+				b.publishState()
+			}
+		}
+	}
+}
+
+func (f *FMS) newMQTTBridge(cfg mqttSettings) *mqttBridge {
+	b := &mqttBridge{
+		f:             f,
+		l:             f.l.Named("mqtt"),
+		cfg:           cfg,
+		last:          make(map[string][]byte),
+		lastTelemetry: make(map[int]time.Time),
+		remaps:        make(chan map[int]string, mqttRemapQueue),
+		done:          make(chan struct{}),
+	}
+	go b.doRemaps()
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(mqttClientID).
+		SetUsername(cfg.User).
+		SetPassword(cfg.Pass).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetWill(b.topic("fms/status"), "offline", 1, true).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			b.l.Warn("Lost connection to broker", "error", err)
+		})
+
+	b.c = mqtt.NewClient(opts)
+	b.l.Info("Connecting to broker", "broker", cfg.Broker)
+	b.c.Connect()
+	return b
+}
+
+func (b *mqttBridge) topic(t string) string {
+	return b.cfg.Prefix + "/" + t
+}
+
+// onConnect is called on every connection to the broker, including
+// reconnects, and so is where subscriptions are made.
+func (b *mqttBridge) onConnect(c mqtt.Client) {
+	b.l.Info("Connected to broker")
+
+	// Anything retained may have been lost if the broker
+	// restarted, so everything is published again.
+	b.resync.Store(true)
+	c.Publish(b.topic("fms/status"), 1, true, "online")
+
+	if !b.cfg.AcceptCommands {
+		return
+	}
+	tok := c.Subscribe(b.topic("command/remap"), 1, b.handleRemap)
+	if tok.WaitTimeout(mqttTimeout) && tok.Error() != nil {
+		b.l.Error("Could not subscribe to commands", "error", tok.Error())
+	}
+}
+
+func (b *mqttBridge) close() {
+	close(b.done)
+	b.l.Info("Disconnecting from broker")
+	if b.c.IsConnected() {
+		b.c.Publish(b.topic("fms/status"), 1, true, "offline").WaitTimeout(mqttTimeout)
+	}
+	b.c.Disconnect(250)
+}
+
+// publishState publishes anything that has changed since the last
+// time it was called.
+func (b *mqttBridge) publishState() {
+	if b.resync.Swap(false) {
+		b.last = make(map[string][]byte)
+	}
+
+	b.publishRetained("field/hud", b.f.hudModel())
+
+	if rec, err := b.f.archive.Current(); err == nil {
+		b.publishRetained("match/current", mqttMatch{
+			ID:      rec.ID,
+			Number:  rec.Number,
+			Mapping: rec.Mapping,
+			Mapped:  rec.Mapped,
+			Started: rec.Started,
+			Stopped: rec.Stopped,
+			Running: rec.Running(),
+		})
+	}
+
+	for team := range b.f.c.Teams {
+		b.f.connectedMutex.RLock()
+		_, dsConnected := b.f.connectedDS[team]
+		_, gizmoConnected := b.f.connectedGizmo[team]
+		b.f.connectedMutex.RUnlock()
+
+		b.f.metaMutex.RLock()
+		dsMeta := b.f.dsMeta[team]
+		gizmoMeta := b.f.gizmoMeta[team]
+		b.f.metaMutex.RUnlock()
+
+		b.publishRetained(fmt.Sprintf("team/%d/ds", team), mqttConnState{dsConnected, dsMeta})
+		b.publishRetained(fmt.Sprintf("team/%d/gizmo", team), mqttConnState{gizmoConnected, gizmoMeta})
+	}
+
+	b.f.robotTelemetryMutex.RLock()
+	fresh := make(map[int]telemetrySample)
+	for team, s := range b.f.robotTelemetry {
+		if s.Time.After(b.lastTelemetry[team]) {
+			fresh[team] = newTelemetrySample(s)
+			b.lastTelemetry[team] = s.Time
+		}
+	}
+	b.f.robotTelemetryMutex.RUnlock()
+
+	for team, s := range fresh {
+		b.publish(fmt.Sprintf("team/%d/telemetry", team), false, s)
+	}
+}
+
+// publishRetained publishes a retained value if it differs from what
+// was last published to the topic.
+func (b *mqttBridge) publishRetained(topic string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		b.l.Warn("Could not marshal value", "topic", topic, "error", err)
+		return
+	}
+	if string(b.last[topic]) == string(data) {
+		return
+	}
+	b.last[topic] = data
+	b.c.Publish(b.topic(topic), 0, true, data)
+}
+
+func (b *mqttBridge) publish(topic string, retained bool, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		b.l.Warn("Could not marshal value", "topic", topic, "error", err)
+		return
+	}
+	b.c.Publish(b.topic(topic), 0, retained, data)
+}
+
+// handleRemap is called by the client for each remap command.  The
+// command is checked and queued here, and the result is published
+// once it has been applied.
+func (b *mqttBridge) handleRemap(c mqtt.Client, msg mqtt.Message) {
+	mapping := make(map[int]string)
+	if err := json.Unmarshal(msg.Payload(), &mapping); err != nil {
+		b.l.Warn("Bad remap command", "error", err, "payload", string(msg.Payload()))
+		b.publish("command/result", false, mqttCommandResult{Command: "remap", Error: err.Error()})
+		return
+	}
+
+	select {
+	case b.remaps <- mapping:
+	default:
+		b.l.Warn("Too many remap commands waiting, dropping one")
+		b.publish("command/result", false, mqttCommandResult{Command: "remap", Error: "too many commands are waiting"})
+	}
+}
+
+// doRemaps applies the queued remap commands one at a time until the
+// bridge is closed.
+func (b *mqttBridge) doRemaps() {
+	for {
+		select {
+		case <-b.done:
+			return
+		case mapping := <-b.remaps:
+			res := mqttCommandResult{Command: "remap", OK: true}
+			if err := b.f.applyMapping(0, mapping); err != nil {
+				b.l.Error("Error remapping teams from MQTT", "error", err)
+				res.OK = false
+				res.Error = err.Error()
+			} else {
+				b.l.Info("Remapped teams from MQTT", "map", mapping)
+			}
+			b.publish("command/result", false, res)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package fms
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/hashicorp/go-hclog"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+	"github.com/gizmo-platform/gizmo/pkg/eventstream"
+	"github.com/gizmo-platform/gizmo/pkg/match"
+	"github.com/gizmo-platform/gizmo/pkg/metrics"
+)
+
+// The MQTT bridge needs a real broker to test against, so these tests
+// are skipped unless GIZMO_TEST_MQTT_BROKER is set.  The compose file
+// in testdata/mqtt runs a suitable one.
+
+type testTLM struct {
+	mutex   sync.Mutex
+	current map[int]string
+}
+
+func (t *testTLM) GetFieldForTeam(int) (string, error) { return "none:none", nil }
+func (t *testTLM) GetActualDS(string) (int, error)     { return 0, nil }
+
+func (t *testTLM) GetCurrentMapping() (map[int]string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	out := make(map[int]string, len(t.current))
+	for team, quad := range t.current {
+		out[team] = quad
+	}
+	return out, nil
+}
+
+func (t *testTLM) InsertOnDemandMap(m map[int]string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.current = m
+	return nil
+}
+
+func (t *testTLM) GetStageMapping() (map[int]string, error) { return nil, nil }
+func (t *testTLM) InsertStageMapping(map[int]string) error  { return nil }
+func (t *testTLM) CommitStagedMap() error                   { return nil }
+
+func newMQTTTestFMS(t *testing.T) (*FMS, *testTLM) {
+	t.Helper()
+
+	tlm := &testTLM{current: map[int]string{}}
+	f := &FMS{
+		c: &config.FMSConfig{
+			Teams: map[int]*config.Team{1234: {Number: 1234, Name: "Test"}},
+			// Field 2 has been removed, so field numbers
+			// are not contiguous.
+			Fields: map[int]*config.Field{0: {ID: 1}, 2: {ID: 3}},
+		},
+		l:                   hclog.NewNullLogger(),
+		es:                  eventstream.New(hclog.NewNullLogger()),
+		tlm:                 tlm,
+		archive:             match.New(match.WithDirectory(t.TempDir())),
+		metrics:             newFMSMetrics(prometheus.NewRegistry()),
+		quads:               []string{"field1:red", "field1:blue", "field3:red", "field3:blue"},
+		connectedDS:         map[int]time.Time{},
+		connectedGizmo:      map[int]time.Time{},
+		connectedMutex:      new(sync.RWMutex),
+		gizmoMeta:           map[int]config.GizmoMeta{},
+		dsMeta:              map[int]config.DSMeta{},
+		metaMutex:           new(sync.RWMutex),
+		dsPresent:           map[string]int{},
+		dsPresentMutex:      new(sync.RWMutex),
+		alerts:              map[string]*alert{},
+		alertMutex:          new(sync.RWMutex),
+		robotTelemetry:      map[int]metrics.Sample{},
+		telemetryHistory:    map[int][]telemetrySample{},
+		robotTelemetryMutex: new(sync.RWMutex),
+		stagedMutex:         new(sync.RWMutex),
+		mapMutex:            new(sync.Mutex),
+		webhookMutex:        new(sync.RWMutex),
+	}
+	return f, tlm
+}
+
+func TestMQTTBridge(t *testing.T) {
+	broker := os.Getenv("GIZMO_TEST_MQTT_BROKER")
+	if broker == "" {
+		t.Skip("GIZMO_TEST_MQTT_BROKER is not set")
+	}
+
+	f, tlm := newMQTTTestFMS(t)
+	prefix := fmt.Sprintf("gizmotest%d", time.Now().UnixNano())
+
+	msgs := make(chan mqtt.Message, 64)
+	sub := mqtt.NewClient(mqtt.NewClientOptions().AddBroker(broker).SetClientID(prefix + "-sub"))
+	if tok := sub.Connect(); !tok.WaitTimeout(mqttTimeout) || tok.Error() != nil {
+		t.Fatalf("Could not connect to broker: %v", tok.Error())
+	}
+	defer sub.Disconnect(250)
+	tok := sub.Subscribe(prefix+"/#", 1, func(_ mqtt.Client, m mqtt.Message) { msgs <- m })
+	if !tok.WaitTimeout(mqttTimeout) || tok.Error() != nil {
+		t.Fatalf("Could not subscribe: %v", tok.Error())
+	}
+
+	b := f.newMQTTBridge(mqttSettings{Broker: broker, Prefix: prefix, AcceptCommands: true})
+	defer b.close()
+
+	wait := func(topic string) []byte {
+		t.Helper()
+		timeout := time.After(mqttTimeout)
+		for {
+			select {
+			case m := <-msgs:
+				if m.Topic() == prefix+"/"+topic {
+					return m.Payload()
+				}
+			case <-timeout:
+				t.Fatalf("Nothing was published to %s", topic)
+			}
+		}
+	}
+
+	if status := wait("fms/status"); string(status) != "online" {
+		t.Errorf("Status is %q, expected online", status)
+	}
+
+	b.publishState()
+	hud := [][]hudQuad{}
+	if err := json.Unmarshal(wait("field/hud"), &hud); err != nil {
+		t.Fatalf("Bad HUD payload: %v", err)
+	}
+	if len(hud) != 2 || len(hud[0]) != 2 || len(hud[1]) != 2 {
+		t.Errorf("HUD should have two fields of two quads, got %v", hud)
+	}
+
+	// The bridge subscribes to commands in its connect handler,
+	// which may still be running.
+	time.Sleep(time.Millisecond * 250)
+	sub.Publish(prefix+"/command/remap", 1, false, `{"1234":"field3:red"}`)
+	res := mqttCommandResult{}
+	if err := json.Unmarshal(wait("command/result"), &res); err != nil {
+		t.Fatalf("Bad result payload: %v", err)
+	}
+	if !res.OK {
+		t.Fatalf("Remap failed: %s", res.Error)
+	}
+	if m, _ := tlm.GetCurrentMapping(); m[1234] != "field3:red" {
+		t.Errorf("Team was not remapped, mapping is %v", m)
+	}
+
+	sub.Publish(prefix+"/command/remap", 1, false, `not json`)
+	if err := json.Unmarshal(wait("command/result"), &res); err != nil {
+		t.Fatalf("Bad result payload: %v", err)
+	}
+	if res.OK {
+		t.Error("A bad remap command was accepted")
+	}
+}
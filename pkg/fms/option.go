@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/gizmo-platform/gizmo/pkg/config"
 )
@@ -84,3 +85,58 @@ func WithNetController(nc NetController) Option {
 		return nil
 	}
 }
+
+// WithMatchArchive injects the archive that match records and the
+// events that occur during matches will be written to.
+func WithMatchArchive(a MatchArchive) Option {
+	return func(f *FMS) error {
+		f.archive = a
+		return nil
+	}
+}
+
+// WithScheduleStore injects the store that the qualification
+// schedule is generated into.
+func WithScheduleStore(s ScheduleStore) Option {
+	return func(f *FMS) error {
+		f.quals = s
+		return nil
+	}
+}
+
+// WithBracketStore injects the store that the elimination bracket is
+// kept in.
+func WithBracketStore(b BracketStore) Option {
+	return func(f *FMS) error {
+		f.bracket = b
+		return nil
+	}
+}
+
+// WithPracticeStore injects the store that practice field bookings
+// are kept in.
+func WithPracticeStore(p PracticeStore) Option {
+	return func(f *FMS) error {
+		f.practice = p
+		return nil
+	}
+}
+
+// WithInspectionStore injects the store that robot inspections are
+// kept in.
+func WithInspectionStore(i InspectionStore) Option {
+	return func(f *FMS) error {
+		f.inspections = i
+		return nil
+	}
+}
+
+// WithPrometheusRegistry sets the registry that the FMS registers its
+// own metrics into and serves on /metrics.  This allows other
+// components to share the same endpoint.
+func WithPrometheusRegistry(r *prometheus.Registry) Option {
+	return func(f *FMS) error {
+		f.promRegistry = r
+		return nil
+	}
+}
@@ -2,18 +2,50 @@ package fms
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
+
 	"github.com/gizmo-platform/gizmo/pkg/config"
+	"github.com/gizmo-platform/gizmo/pkg/match"
 )
 
 // This file includes integration code to make the Gizmo work well
 // with the BEST Robotics PC Scoring Manager (PCSM).  PCSM serializes
 // its internal match format and sends that across the wire, which
 // then needs to be deserialized and converted into a TLM mapping.
+//
+// PCSM can also ask whether the teams in a match are ready to go
+// before the scorekeeper starts it, and tells the FMS when the match
+// actually starts and stops so that the match record reflects when
+// the robots were in play.
+
+func init() {
+	registerIntegration(&integration{
+		IntegrationInfo: config.IntegrationInfo{
+			ID:          config.IntegrationPCSM,
+			Slug:        "pcsm",
+			Name:        "BEST Robotics PCSM",
+			Description: "Allows PCSM to map teams to fields, check that teams are ready, and report when matches start and stop.  If matches are staged, PCSM can queue the next match while the current one is being played, and the staged match is mapped when it is committed from PCSM or the stage mapping page.",
+			Settings: []config.IntegrationSetting{
+				{Key: "stage", Label: "Stage Matches", Type: config.SettingBool, Default: "false"},
+			},
+		},
+		Public: func(f *FMS, r chi.Router) {
+			r.Post("/admin/map/pcsm", f.remapTeamsPCSM)
+			r.Post("/admin/map/pcsm/commit", f.commitTeamsPCSM)
+			r.Get("/admin/match/pcsm/{match}/ready", f.pcsmMatchReadiness)
+			r.Post("/admin/match/pcsm/start", f.pcsmMatchStart)
+			r.Post("/admin/match/pcsm/stop", f.pcsmMatchStop)
+		},
+	})
+}
 
 type pcsmMatch struct {
 	Number int `json:"matchNumber"`
@@ -31,6 +63,20 @@ type pcsmTeam struct {
 	Quadrant string
 }
 
+// names returns the names PCSM has for each team in the match.
+func (p *pcsmMatch) names() map[int]string {
+	out := make(map[int]string, len(p.Fields)*4)
+	for _, field := range p.Fields {
+		for _, t := range field.Teams {
+			if t.Number == 0 {
+				continue
+			}
+			out[t.Number] = t.Name
+		}
+	}
+	return out
+}
+
 func (p *pcsmMatch) toTLM() map[int]string {
 	out := make(map[int]string, len(p.Fields)*4)
 
@@ -46,13 +92,183 @@ func (p *pcsmMatch) toTLM() map[int]string {
 	return out
 }
 
-func (f *FMS) remapTeamsPCSM(w http.ResponseWriter, r *http.Request) {
-	if !f.c.Integrations.Enabled(config.IntegrationPCSM) {
-		w.WriteHeader(http.StatusPreconditionFailed)
-		w.Write([]byte("Integration is not enabled!"))
+// pcsmReadiness reports whether each team in a match is ready to
+// play.  It is laid out the same way as the match PCSM sends so that
+// PCSM can line it up with its own view of the match.
+type pcsmReadiness struct {
+	Number int `json:"matchNumber"`
+	Ready  bool
+	Fields []pcsmFieldReadiness
+}
+
+type pcsmFieldReadiness struct {
+	Number int `json:"fieldNumber"`
+	Teams  []pcsmTeamReadiness
+}
+
+type pcsmTeamReadiness struct {
+	Number   int `json:"teamNumber"`
+	Name     string
+	Quadrant string
+
+	DSConnected     bool
+	GizmoConnected  bool
+	CompatOK        bool
+	CorrectQuadrant bool
+	Ready           bool
+	Problems        []string
+}
+
+// pcsmCurrentMatch returns the record for the match that is mapped,
+// and checks that it is the match PCSM is asking about.  A number of
+// zero means whatever match is mapped.
+func (f *FMS) pcsmCurrentMatch(w http.ResponseWriter, number int) (match.Record, bool) {
+	if staged, ok := f.staged(); ok && number != 0 && staged.Number == number {
+		http.Error(w, fmt.Sprintf("match %d is staged but has not been committed", number), http.StatusConflict)
+		return match.Record{}, false
+	}
+	rec, err := f.archive.Current()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return match.Record{}, false
+	}
+	if number != 0 && rec.Number != number {
+		http.Error(w, fmt.Sprintf("match %d is not mapped, match %d is", number, rec.Number), http.StatusConflict)
+		return match.Record{}, false
+	}
+	return rec, true
+}
+
+// teamReadiness checks a single team against the quad it is mapped
+// to.
+func (f *FMS) teamReadiness(team int, quad string) pcsmTeamReadiness {
+	parts := strings.SplitN(quad, ":", 2)
+	tr := pcsmTeamReadiness{Number: team, Quadrant: strings.ToUpper(parts[len(parts)-1])}
+
+	ts, err := f.teamStatus(team)
+	if err != nil {
+		tr.Problems = []string{"Team is not on the roster"}
+		return tr
+	}
+	tr.Name = ts.Name
+	tr.DSConnected = ts.DSConnected
+	tr.GizmoConnected = ts.GizmoConnected
+
+	// A driver's station can only reach the FMS from the quad it
+	// is mapped to, so one that is connected but hasn't been seen
+	// by the field yet is where it should be.
+	tr.CorrectQuadrant = ts.Actual == quad || (ts.Actual == "" && ts.DSConnected)
+	tr.CompatOK = (!ts.DSConnected || (ts.DSVersionOK && ts.DSBootOK)) &&
+		(!ts.GizmoConnected || (ts.GizmoHardwareOK && ts.GizmoFirmwareOK))
+
+	tr.Problems = []string{}
+	if !tr.DSConnected {
+		tr.Problems = append(tr.Problems, "Driver's station is not connected")
+	}
+	if !tr.GizmoConnected {
+		tr.Problems = append(tr.Problems, "Gizmo is not connected")
+	}
+	if !tr.CompatOK {
+		tr.Problems = append(tr.Problems, "Hardware or software is not compatible")
+	}
+	if !tr.CorrectQuadrant {
+		if ts.Actual != "" {
+			tr.Problems = append(tr.Problems, "Plugged into "+quadPort(ts.Actual))
+		} else {
+			tr.Problems = append(tr.Problems, "Not plugged into the field")
+		}
+	}
+	tr.Ready = len(tr.Problems) == 0
+	return tr
+}
+
+func (f *FMS) matchReadiness(rec match.Record) pcsmReadiness {
+	out := pcsmReadiness{Number: rec.Number, Ready: len(rec.Mapping) > 0}
+
+	fields := make(map[int]*pcsmFieldReadiness)
+	for team, quad := range rec.Mapping {
+		fnum, _ := strconv.Atoi(strings.TrimPrefix(strings.SplitN(quad, ":", 2)[0], "field"))
+		if _, ok := fields[fnum]; !ok {
+			fields[fnum] = &pcsmFieldReadiness{Number: fnum, Teams: []pcsmTeamReadiness{}}
+		}
+		tr := f.teamReadiness(team, quad)
+		out.Ready = out.Ready && tr.Ready
+		fields[fnum].Teams = append(fields[fnum].Teams, tr)
+	}
+
+	out.Fields = []pcsmFieldReadiness{}
+	for _, field := range fields {
+		sort.Slice(field.Teams, func(i, j int) bool {
+			return field.Teams[i].Quadrant < field.Teams[j].Quadrant
+		})
+		out.Fields = append(out.Fields, *field)
+	}
+	sort.Slice(out.Fields, func(i, j int) bool {
+		return out.Fields[i].Number < out.Fields[j].Number
+	})
+	return out
+}
+
+func (f *FMS) pcsmMatchReadiness(w http.ResponseWriter, r *http.Request) {
+	number, err := strconv.Atoi(chi.URLParam(r, "match"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rec, ok := f.pcsmCurrentMatch(w, number)
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(f.matchReadiness(rec))
+}
+
+func (f *FMS) pcsmMatchStart(w http.ResponseWriter, r *http.Request) {
+	f.pcsmMatchState(w, r, true)
+}
+
+func (f *FMS) pcsmMatchStop(w http.ResponseWriter, r *http.Request) {
+	f.pcsmMatchState(w, r, false)
+}
+
+// pcsmMatchState starts or stops the current match.  PCSM sends the
+// match number so that a notification for a match that isn't mapped
+// doesn't get applied to the wrong record.
+func (f *FMS) pcsmMatchState(w http.ResponseWriter, r *http.Request, start bool) {
+	m := pcsmMatch{}
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		f.l.Warn("Error decoding match state from PCSM", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rec, ok := f.pcsmCurrentMatch(w, m.Number)
+	if !ok {
+		return
+	}
+
+	event := webhookMatchStarted
+	change := f.archive.Start
+	if !start {
+		event = webhookMatchStopped
+		change = f.archive.Stop
+	}
+
+	if err := change(); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, match.ErrMatchStarted) || errors.Is(err, match.ErrMatchNotRunning) {
+			status = http.StatusConflict
+		}
+		f.l.Warn("Could not change match state", "match", rec.Number, "start", start, "error", err)
+		http.Error(w, err.Error(), status)
 		return
 	}
 
+	f.l.Info("Match state changed by PCSM", "match", rec.Number, "running", start)
+	f.notifyWebhooks(event, webhookMatch{ID: rec.ID, Number: rec.Number, Mapping: rec.Mapping})
+}
+
+func (f *FMS) remapTeamsPCSM(w http.ResponseWriter, r *http.Request) {
 	match := pcsmMatch{}
 
 	buf, _ := io.ReadAll(r.Body)
@@ -64,7 +280,17 @@ func (f *FMS) remapTeamsPCSM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := f.tlm.InsertOnDemandMap(match.toTLM()); err != nil {
+	if f.c.IntegrationSettingBool(config.IntegrationPCSM, "stage") {
+		if err := f.stageMapping(stagedMatch{Number: match.Number, Names: match.names()}, match.toTLM()); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			f.l.Warn("Error staging match", "error", err)
+			return
+		}
+		f.l.Info("Staged match from PCSM", "match", match.Number)
+		return
+	}
+
+	if err := f.applyMapping(match.Number, match.toTLM()); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		f.l.Warn("Error inserting on-demand match", "error", err)
 		return
@@ -77,3 +303,36 @@ func (f *FMS) remapTeamsPCSM(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// commitTeamsPCSM commits the staged match.  PCSM may send the match
+// number it expects to be staged, in which case nothing is committed
+// if a different match is staged.
+func (f *FMS) commitTeamsPCSM(w http.ResponseWriter, r *http.Request) {
+	m := pcsmMatch{}
+	buf, _ := io.ReadAll(r.Body)
+	if len(buf) > 0 {
+		if err := json.Unmarshal(buf, &m); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			f.l.Warn("Error decoding commit from PCSM", "error", err, "data", string(buf))
+			return
+		}
+	}
+
+	if stage, err := f.tlm.GetStageMapping(); err == nil && len(stage) == 0 {
+		http.Error(w, "no match is staged", http.StatusConflict)
+		return
+	}
+
+	staged, ok := f.staged()
+	if m.Number != 0 && (!ok || staged.Number != m.Number) {
+		http.Error(w, fmt.Sprintf("match %d is not staged", m.Number), http.StatusConflict)
+		return
+	}
+
+	if err := f.commitStagedMap(); err != nil {
+		f.l.Error("Error commiting staged mapping!", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.l.Info("Committed staged match from PCSM", "match", staged.Number)
+}
@@ -0,0 +1,500 @@
+package fms
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+	"rsc.io/qr"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+	"github.com/gizmo-platform/gizmo/pkg/inspection"
+	"github.com/gizmo-platform/gizmo/pkg/match"
+)
+
+// Teams can log in to a portal that shows them their own schedule,
+// match history, and network credentials, and lets them book practice
+// slots for themselves, without having to ask the field crew.  Team logins are generated from the roster and are kept
+// entirely separate from the staff logins, so a team can never reach
+// anything other than the portal.  Teams log in through the same form
+// as staff with a username of "team" followed by their number.
+
+const (
+	portalCookie      = "gizmo-team"
+	portalSessionLife = time.Hour * 12
+
+	// portalPasswordLen is how long generated passwords are.
+	// They are stored as bcrypt hashes so that the config file
+	// doesn't give them away.
+	portalPasswordLen = 10
+	portalAlphabet    = "abcdefghjkmnpqrstuvwxyz23456789"
+
+	// connectionLogLen is how many connection events are kept for
+	// each team.
+	connectionLogLen = 50
+)
+
+type portalTeamKey struct{}
+
+// portalSession is a team that has logged in to the portal.  The hash
+// is kept so that regenerating a team's password logs them out.
+type portalSession struct {
+	Team    int
+	Hash    string
+	Expires time.Time
+}
+
+// portalLogin is a generated login.  The password is only ever
+// returned when it is generated.
+type portalLogin struct {
+	Team     int
+	Name     string
+	Username string
+	Password string
+}
+
+type portalGenerate struct {
+	Teams   []int
+	Missing bool
+}
+
+// connectionEvent is a device connecting to or disconnecting from the
+// FMS.
+type connectionEvent struct {
+	Time      time.Time
+	Device    string
+	Connected bool
+}
+
+// portalTeamInfo is everything a team may see about itself.  It is
+// deliberately not a config.Team so that nothing is shown by
+// accident if more is added to the roster.
+type portalTeamInfo struct {
+	Number int
+	Name   string
+	SSID   string
+	PSK    string
+	CIDR   string
+}
+
+type portalQual struct {
+	Number int
+	Quad   string
+	Played bool
+}
+
+type portalBracketMatch struct {
+	Name   string
+	Seed   int
+	Ready  bool
+	Played bool
+	Won    bool
+}
+
+type portalMatch struct {
+	ID     int
+	Number int
+	Quad   string
+	Mapped time.Time
+}
+
+type portalView struct {
+	Team        portalTeamInfo
+	Quals       []portalQual
+	Practice    []practiceSlot
+	Bracket     []portalBracketMatch
+	Matches     []portalMatch
+	Connections []connectionEvent
+	Inspection  *inspection.Record
+}
+
+// portalHash hashes a portal password for storage in the config.
+func portalHash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func portalPassword() (string, error) {
+	out := make([]byte, portalPasswordLen)
+	max := big.NewInt(int64(len(portalAlphabet)))
+	for i := range out {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out[i] = portalAlphabet[n.Int64()]
+	}
+	return string(out), nil
+}
+
+// portalCheckPassword returns the team that the username and password
+// belong to, if they are a valid team login.
+func (f *FMS) portalCheckPassword(username, password string) (int, bool) {
+	if !f.c.TeamPortalEnabled || !strings.HasPrefix(username, "team") {
+		return 0, false
+	}
+	team, err := strconv.Atoi(strings.TrimPrefix(username, "team"))
+	if err != nil {
+		return 0, false
+	}
+	t, ok := f.c.Teams[team]
+	if !ok || t.PortalHash == "" {
+		return 0, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(t.PortalHash), []byte(password)) != nil {
+		return 0, false
+	}
+	return team, true
+}
+
+// portalTeam returns the team that is logged in to the portal on this
+// request, if any.
+func (f *FMS) portalTeam(r *http.Request) (int, bool) {
+	if !f.c.TeamPortalEnabled {
+		return 0, false
+	}
+	c, err := r.Cookie(portalCookie)
+	if err != nil {
+		return 0, false
+	}
+
+	f.portalMutex.RLock()
+	s, ok := f.portalSessions[c.Value]
+	f.portalMutex.RUnlock()
+	if !ok || time.Now().After(s.Expires) {
+		return 0, false
+	}
+	t, ok := f.c.Teams[s.Team]
+	if !ok || t.PortalHash != s.Hash {
+		return 0, false
+	}
+	return s.Team, true
+}
+
+// portalLoginHandler logs teams in to the portal, and passes anyone
+// else through to the staff login.
+func (f *FMS) portalLoginHandler(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		team, ok := f.portalCheckPassword(r.FormValue("username"), r.FormValue("password"))
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		buf := make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		token := hex.EncodeToString(buf)
+		expires := time.Now().Add(portalSessionLife)
+
+		f.portalMutex.Lock()
+		for k, s := range f.portalSessions {
+			if time.Now().After(s.Expires) {
+				delete(f.portalSessions, k)
+			}
+		}
+		f.portalSessions[token] = portalSession{Team: team, Hash: f.c.Teams[team].PortalHash, Expires: expires}
+		f.portalMutex.Unlock()
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     portalCookie,
+			Value:    token,
+			Path:     "/",
+			Expires:  expires,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		f.l.Info("Team logged in to portal", "team", team)
+		http.Redirect(w, r, "/ui/portal/", http.StatusSeeOther)
+	}
+}
+
+// portalLogoutHandler ends the team's portal session, if there is one,
+// and then logs out of the staff login as usual.
+func (f *FMS) portalLogoutHandler(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie(portalCookie); err == nil {
+			f.portalMutex.Lock()
+			delete(f.portalSessions, c.Value)
+			f.portalMutex.Unlock()
+			http.SetCookie(w, &http.Cookie{Name: portalCookie, Path: "/", MaxAge: -1})
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// requirePortal only allows teams that are logged in to the portal.
+// The team is stored in the request context for the handlers.
+func (f *FMS) requirePortal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		team, ok := f.portalTeam(r)
+		if !ok {
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				http.Error(w, "Log in to the team portal first", http.StatusUnauthorized)
+				return
+			}
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), portalTeamKey{}, team)))
+	})
+}
+
+// logConnection notes that a team's device connected or disconnected.
+func (f *FMS) logConnection(team int, device string, connected bool) {
+	f.connectionMutex.Lock()
+	defer f.connectionMutex.Unlock()
+
+	log := append(f.connectionLog[team], connectionEvent{Time: time.Now(), Device: device, Connected: connected})
+	if len(log) > connectionLogLen {
+		log = log[len(log)-connectionLogLen:]
+	}
+	f.connectionLog[team] = log
+}
+
+// portalView assembles everything the portal shows a team.
+func (f *FMS) portalView(team int) portalView {
+	t := f.c.Teams[team]
+	v := portalView{
+		Team: portalTeamInfo{
+			Number: t.Number,
+			Name:   t.Name,
+			SSID:   t.SSID,
+			PSK:    t.PSK,
+			CIDR:   t.CIDR,
+		},
+		Quals:    []portalQual{},
+		Practice: []practiceSlot{},
+		Bracket:  []portalBracketMatch{},
+		Matches:  []portalMatch{},
+	}
+
+	played := make(map[int]bool)
+	records, err := f.archive.List()
+	if err != nil {
+		f.l.Warn("Could not list matches for portal", "error", err)
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		rec := records[i]
+		played[rec.Number] = true
+		if quad, ok := rec.Mapping[team]; ok {
+			v.Matches = append(v.Matches, portalMatch{ID: rec.ID, Number: rec.Number, Quad: quad, Mapped: rec.Mapped})
+		}
+	}
+
+	for _, m := range f.quals.Get().Matches {
+		if quad, ok := m.Mapping[team]; ok {
+			v.Quals = append(v.Quals, portalQual{Number: m.Number, Quad: quad, Played: played[m.Number]})
+		}
+	}
+
+	now := time.Now()
+	for _, b := range f.practice.List() {
+		if b.Team == team && !b.Ended && now.Before(b.End) {
+			v.Practice = append(v.Practice, f.practiceSlot(b))
+		}
+	}
+
+	b := f.bracket.Get()
+	seed := 0
+	for _, a := range b.Alliances {
+		for _, member := range a.Teams {
+			if member == team {
+				seed = a.Seed
+			}
+		}
+	}
+	for _, m := range b.Matches {
+		if seed != 0 && (m.Alliances[0] == seed || m.Alliances[1] == seed) {
+			v.Bracket = append(v.Bracket, portalBracketMatch{
+				Name:   m.Name,
+				Seed:   seed,
+				Ready:  m.Ready(),
+				Played: m.Winner != 0,
+				Won:    m.Winner == seed,
+			})
+		}
+	}
+
+	f.connectionMutex.RLock()
+	v.Connections = append([]connectionEvent{}, f.connectionLog[team]...)
+	f.connectionMutex.RUnlock()
+	sort.Slice(v.Connections, func(i, j int) bool { return v.Connections[i].Time.After(v.Connections[j].Time) })
+
+	if rec, ok := f.inspections.Latest()[team]; ok {
+		v.Inspection = &rec
+	}
+	return v
+}
+
+// wifiEscape escapes the characters that have meaning in a WiFi QR
+// code.
+func wifiEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, `:`, `\:`, `"`, `\"`)
+	return r.Replace(s)
+}
+
+func (f *FMS) apiGetPortal(w http.ResponseWriter, r *http.Request) {
+	team := r.Context().Value(portalTeamKey{}).(int)
+	json.NewEncoder(w).Encode(f.portalView(team))
+}
+
+func (f *FMS) apiGetPortalMatchTelemetry(w http.ResponseWriter, r *http.Request) {
+	team := r.Context().Value(portalTeamKey{}).(int)
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Teams may only download telemetry from matches they were
+	// in, and only their own.
+	rec, err := f.archive.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if _, ok := rec.Mapping[team]; !ok {
+		http.Error(w, "Your team was not in this match", http.StatusForbidden)
+		return
+	}
+
+	name := TelemetryCSVName(team)
+	src, err := f.archive.OpenArtifact(id, name)
+	if errors.Is(err, match.ErrNoSuchArtifact) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer src.Close()
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"match-%05d-%s\"", id, name))
+	io.Copy(w, src)
+}
+
+func (f *FMS) apiGeneratePortalLogins(w http.ResponseWriter, r *http.Request) {
+	req := new(portalGenerate)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	teams := []*config.Team{}
+	switch {
+	case req.Missing:
+		for _, t := range f.c.SortedTeams() {
+			if t.PortalHash == "" {
+				teams = append(teams, t)
+			}
+		}
+	case len(req.Teams) == 0:
+		teams = f.c.SortedTeams()
+	default:
+		for _, num := range req.Teams {
+			t, ok := f.c.Teams[num]
+			if !ok {
+				http.Error(w, fmt.Sprintf("team %d is not on the roster", num), http.StatusBadRequest)
+				return
+			}
+			teams = append(teams, t)
+		}
+	}
+
+	logins := []portalLogin{}
+	for _, t := range teams {
+		password, err := portalPassword()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		t.PortalHash, err = portalHash(password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logins = append(logins, portalLogin{
+			Team:     t.Number,
+			Name:     t.Name,
+			Username: fmt.Sprintf("team%d", t.Number),
+			Password: password,
+		})
+	}
+
+	if err := f.c.Save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+	f.l.Info("Generated team portal logins", "count", len(logins))
+	f.es.PublishActionComplete("Portal Login Generation")
+	json.NewEncoder(w).Encode(logins)
+}
+
+func (f *FMS) apiUpdatePortal(w http.ResponseWriter, r *http.Request) {
+	cTmp := new(config.FMSConfig)
+
+	if err := json.NewDecoder(r.Body).Decode(&cTmp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// We do this rather than deserializing into the main config
+	// struct to ensure that its not possible to rewrite other
+	// unrelated parts of the config via this API.
+	f.c.TeamPortalEnabled = cTmp.TeamPortalEnabled
+
+	if err := f.c.Save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+	f.es.PublishActionComplete("Configuration Save")
+}
+
+func (f *FMS) uiViewPortalSetup(w http.ResponseWriter, r *http.Request) {
+	f.doTemplate(w, r, "views/setup/portal.p2", pongo2.Context{"cfg": f.c, "roster": f.c.SortedTeams()})
+}
+
+func (f *FMS) uiViewPortal(w http.ResponseWriter, r *http.Request) {
+	team := r.Context().Value(portalTeamKey{}).(int)
+	ctx := pongo2.Context{
+		"portal": f.portalView(team),
+		"quads":  f.practiceQuads(),
+	}
+	f.doTemplate(w, r, "views/portal/home.p2", ctx)
+}
+
+func (f *FMS) uiViewPortalWifiQR(w http.ResponseWriter, r *http.Request) {
+	t := f.c.Teams[r.Context().Value(portalTeamKey{}).(int)]
+
+	code, err := qr.Encode(fmt.Sprintf("WIFI:T:WPA;S:%s;P:%s;;", wifiEscape(t.SSID), wifiEscape(t.PSK)), qr.M)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(code.PNG())
+}
@@ -0,0 +1,339 @@
+package fms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gizmo-platform/gizmo/pkg/practice"
+)
+
+// Teams book practice quads for a slot of time.  When the slot starts
+// the team is mapped to the quad, and when it ends they are unmapped
+// again.  AutoMap leaves booked quads alone for as long as the
+// booking is active so that someone plugging into the wrong quad
+// can't take it over.
+
+const (
+	practiceRate = time.Second
+
+	// practiceUpcoming is how many upcoming slots the board shows
+	// for each quad.
+	practiceUpcoming = 3
+)
+
+// practiceSlot is a booking as it is shown on the booking board.
+type practiceSlot struct {
+	ID       int
+	Team     int
+	Name     string
+	Quad     string
+	Start    string
+	End      string
+	BookedBy string
+	Active   bool
+	Skipped  string
+}
+
+type practiceBoardQuad struct {
+	Quad     string
+	Color    string
+	Current  *practiceSlot
+	Upcoming []practiceSlot
+}
+
+type practiceBoardField struct {
+	Field int
+	Quads []practiceBoardQuad
+}
+
+func (f *FMS) doPracticeUpkeep() {
+	ticker := time.NewTicker(practiceRate)
+
+	for {
+		select {
+		case <-f.stop:
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			f.practiceStep(time.Now())
+		}
+	}
+}
+
+// practiceStep maps the teams whose slots have started and unmaps the
+// teams whose slots have ended.  Slots that end are handled first so
+// that back to back bookings on the same quad change over in a single
+// mapping.  Practice only happens on fields that map automatically,
+// so the change is made in place and the match record for the
+// competition fields carries on.
+func (f *FMS) practiceStep(now time.Time) {
+	start, end := f.practice.Due(now)
+	if len(start) == 0 && len(end) == 0 {
+		return
+	}
+
+	started := []practice.Booking{}
+	skipped := []practice.Booking{}
+	skip := func(b practice.Booking, reason string) {
+		f.l.Warn("Practice slot not started", "team", b.Team, "quad", b.Quad, "reason", reason)
+		b.Skipped = reason
+		skipped = append(skipped, b)
+	}
+
+	err := f.updateMapping(mapKindAutomatic, func(m map[int]string) bool {
+		changed := false
+		for _, b := range end {
+			if b.Started && m[b.Team] == b.Quad {
+				f.l.Info("Practice slot ended", "team", b.Team, "quad", b.Quad)
+				delete(m, b.Team)
+				changed = true
+			}
+		}
+		for _, b := range start {
+			// A slot that can't be started is skipped rather
+			// than retried every tick, the team just doesn't
+			// get the quad.
+			if !f.quadIsAutoMapped(b.Quad) {
+				skip(b, "quad is not on a practice field")
+				continue
+			}
+			if quad, ok := m[b.Team]; ok && quad != b.Quad && !f.quadIsAutoMapped(quad) {
+				skip(b, "team is mapped to "+quad)
+				continue
+			}
+			if f.c.InspectionPolicy == "REJECT" && len(f.uninspected(map[int]string{b.Team: b.Quad}, nil)) > 0 {
+				skip(b, "team has not passed inspection")
+				continue
+			}
+			for team, quad := range m {
+				if quad == b.Quad {
+					delete(m, team)
+				}
+			}
+			f.l.Info("Practice slot started", "team", b.Team, "quad", b.Quad)
+			m[b.Team] = b.Quad
+			started = append(started, b)
+			changed = true
+		}
+		return changed
+	})
+	if err != nil {
+		f.l.Error("Error applying practice mapping", "error", err)
+		f.es.PublishError(err)
+		return
+	}
+	if err := f.practice.Mark(started, skipped, end); err != nil {
+		f.l.Warn("Could not save practice bookings", "error", err)
+	}
+}
+
+// practiceBoard assembles the booking board.  Every practice field is
+// shown, along with any other field that still has a booking on it
+// from before it stopped being a practice field.
+func (f *FMS) practiceBoard(now time.Time) []practiceBoardField {
+	fields := make(map[int]bool)
+	for _, field := range f.c.Fields {
+		if field.AutoMap {
+			fields[field.ID] = true
+		}
+	}
+
+	bookings := f.practice.List()
+	byQuad := make(map[string][]practice.Booking)
+	for _, b := range bookings {
+		if b.Ended || !now.Before(b.End) {
+			continue
+		}
+		byQuad[b.Quad] = append(byQuad[b.Quad], b)
+		if n, err := strconv.Atoi(strings.TrimPrefix(strings.SplitN(b.Quad, ":", 2)[0], "field")); err == nil {
+			fields[n] = true
+		}
+	}
+
+	ids := []int{}
+	for id := range fields {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	out := []practiceBoardField{}
+	for _, id := range ids {
+		bf := practiceBoardField{Field: id}
+		for _, color := range []string{"red", "blue", "green", "yellow"} {
+			quad := fmt.Sprintf("field%d:%s", id, color)
+			bq := practiceBoardQuad{Quad: quad, Color: color, Upcoming: []practiceSlot{}}
+			for _, b := range byQuad[quad] {
+				slot := f.practiceSlot(b)
+				switch {
+				case b.Active():
+					bq.Current = &slot
+				case b.Skipped != "":
+				case len(bq.Upcoming) < practiceUpcoming:
+					bq.Upcoming = append(bq.Upcoming, slot)
+				}
+			}
+			bf.Quads = append(bf.Quads, bq)
+		}
+		out = append(out, bf)
+	}
+	return out
+}
+
+// practiceQuads returns the quads that can be booked for practice in
+// sorted order.
+func (f *FMS) practiceQuads() []string {
+	quads := []string{}
+	for _, quad := range f.quads {
+		if f.quadIsAutoMapped(quad) {
+			quads = append(quads, quad)
+		}
+	}
+	sort.Strings(quads)
+	return quads
+}
+
+func (f *FMS) practiceSlot(b practice.Booking) practiceSlot {
+	return practiceSlot{
+		ID:       b.ID,
+		Team:     b.Team,
+		Name:     f.teamName(b.Team),
+		Quad:     b.Quad,
+		Start:    b.Start.Format("15:04"),
+		End:      b.End.Format("15:04"),
+		BookedBy: b.BookedBy,
+		Active:   b.Active(),
+		Skipped:  b.Skipped,
+	}
+}
+
+func (f *FMS) apiGetPracticeBookings(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(f.practice.List())
+}
+
+func (f *FMS) apiBookPractice(w http.ResponseWriter, r *http.Request) {
+	b := practice.Booking{}
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.bookPractice(w, b)
+}
+
+// apiPortalBookPractice lets a team book a practice slot for
+// themselves from the team portal.  Whatever team is in the request
+// is ignored in favor of the one that is logged in.
+func (f *FMS) apiPortalBookPractice(w http.ResponseWriter, r *http.Request) {
+	team := r.Context().Value(portalTeamKey{}).(int)
+
+	b := practice.Booking{}
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	b.Team = team
+	b.BookedBy = fmt.Sprintf("team%d", team)
+	f.bookPractice(w, b)
+}
+
+// bookPractice checks and books a slot, and writes the booking or the
+// reason it couldn't be made to the response.
+func (f *FMS) bookPractice(w http.ResponseWriter, b practice.Booking) {
+	if _, ok := f.c.Teams[b.Team]; !ok {
+		http.Error(w, fmt.Sprintf("team %d is not on the roster", b.Team), http.StatusBadRequest)
+		return
+	}
+	known := false
+	for _, quad := range f.quads {
+		known = known || quad == b.Quad
+	}
+	if !known {
+		http.Error(w, fmt.Sprintf("%s is not a quad on any field", b.Quad), http.StatusBadRequest)
+		return
+	}
+	if !f.quadIsAutoMapped(b.Quad) {
+		http.Error(w, fmt.Sprintf("%s is not on a practice field", b.Quad), http.StatusBadRequest)
+		return
+	}
+
+	b, err := f.practice.Book(b)
+	switch {
+	case errors.Is(err, practice.ErrBadSlot):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	f.es.PublishActionComplete("Practice Booking")
+	json.NewEncoder(w).Encode(b)
+}
+
+func (f *FMS) apiCancelPractice(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	b, err := f.practice.Cancel(id)
+	switch {
+	case errors.Is(err, practice.ErrNoSuchBooking):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+
+	// If the slot was in progress the team comes off the field
+	// now rather than at the end of the slot.
+	if b.Active() {
+		err := f.updateMapping(mapKindAutomatic, func(m map[int]string) bool {
+			if m[b.Team] != b.Quad {
+				return false
+			}
+			delete(m, b.Team)
+			return true
+		})
+		if err != nil {
+			f.l.Error("Error unmapping cancelled practice slot", "error", err)
+			f.es.PublishError(err)
+		}
+	}
+	f.es.PublishActionComplete("Practice Cancellation")
+}
+
+func (f *FMS) apiPracticeBoard(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(f.practiceBoard(time.Now()))
+}
+
+func (f *FMS) uiViewPracticeBookings(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	slots := []practiceSlot{}
+	for _, b := range f.practice.List() {
+		if !b.Ended && now.Before(b.End) {
+			slots = append(slots, f.practiceSlot(b))
+		}
+	}
+
+	ctx := pongo2.Context{
+		"teams": f.c.SortedTeams(),
+		"quads": f.practiceQuads(),
+		"slots": slots,
+	}
+	f.doTemplate(w, r, "views/admin/practice.p2", ctx)
+}
+
+func (f *FMS) uiViewPracticeBoard(w http.ResponseWriter, r *http.Request) {
+	f.doTemplate(w, r, "views/display/practice-board.p2", nil)
+}
@@ -0,0 +1,104 @@
+package fms
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	rconfig "github.com/gizmo-platform/gizmo/pkg/routeros/config"
+)
+
+// Teams frequently plug their driver's station into the wrong
+// quadrant.  The field learns which driver's station is on each port
+// via LLDP, so the FMS can tell when a team is somewhere other than
+// where it is mapped and offer to fix it.  It can either remap the
+// field to match where teams actually are, or tell the volunteer
+// which cable to move.
+
+var (
+	errNotMisplaced = errors.New("team is not in the wrong quadrant")
+)
+
+// quadCorrection describes how to fix a team that is plugged into
+// the wrong quadrant.
+type quadCorrection struct {
+	Team         int
+	Mapped       string
+	MappedPort   string
+	Actual       string
+	ActualPort   string
+	Instructions string
+}
+
+// quadPort describes a quad along with the field port it is cabled
+// to, for example "field1:red (ether2)".
+func quadPort(quad string) string {
+	parts := strings.SplitN(quad, ":", 2)
+	if len(parts) != 2 {
+		return quad
+	}
+	return fmt.Sprintf("%s (%s)", quad, rconfig.QuadToEther(parts[1]))
+}
+
+// actualQuadForTeam returns the quad that the team's driver's station
+// has been detected on, or an empty string if it hasn't been seen.
+func (f *FMS) actualQuadForTeam(team int) string {
+	f.dsPresentMutex.RLock()
+	defer f.dsPresentMutex.RUnlock()
+	for quad, num := range f.dsPresent {
+		if num == team {
+			return quad
+		}
+	}
+	return ""
+}
+
+// quadCorrectionFor works out where a team is mapped versus where
+// they are plugged in.
+func (f *FMS) quadCorrectionFor(team int) (quadCorrection, error) {
+	m, err := f.tlm.GetCurrentMapping()
+	if err != nil {
+		return quadCorrection{}, err
+	}
+
+	mapped := m[team]
+	actual := f.actualQuadForTeam(team)
+	if mapped == "" || actual == "" || mapped == actual {
+		return quadCorrection{}, errNotMisplaced
+	}
+
+	return quadCorrection{
+		Team:       team,
+		Mapped:     mapped,
+		MappedPort: quadPort(mapped),
+		Actual:     actual,
+		ActualPort: quadPort(actual),
+		Instructions: fmt.Sprintf("Move the driver's station cable for team %d from %s to %s",
+			team, quadPort(actual), quadPort(mapped)),
+	}, nil
+}
+
+// remapToActual changes the current mapping so that the team is
+// mapped to the quad they are actually plugged into.  Any team that
+// was mapped to that quad is swapped into the team's old quad so that
+// nobody is left without a mapping.
+func (f *FMS) remapToActual(team int) error {
+	qc, err := f.quadCorrectionFor(team)
+	if err != nil {
+		return err
+	}
+
+	// This is still the same match even though it's on a corrected
+	// mapping, so the change is made in place and the match record
+	// carries on.
+	f.l.Info("Remapping team to detected quad", "team", team, "from", qc.Mapped, "to", qc.Actual)
+	return f.updateMapping(mapKindCorrection, func(m map[int]string) bool {
+		for t, q := range m {
+			if q == qc.Actual {
+				m[t] = qc.Mapped
+			}
+		}
+		m[team] = qc.Actual
+		return true
+	})
+}
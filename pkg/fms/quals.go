@@ -0,0 +1,147 @@
+package fms
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gizmo-platform/gizmo/pkg/schedule"
+)
+
+// Event directors used to build qualification schedules by hand.  The
+// FMS can generate one from the roster instead, and the matches in it
+// can then be staged one at a time as the event runs.
+
+// qualRow is a single match of the schedule as it is shown on the
+// schedule page, with the teams listed in the same order as the quads.
+type qualRow struct {
+	Number int
+	Teams  []int
+	Played bool
+}
+
+// qualQuads returns the quads that qualification matches are played
+// on.  Fields that map automatically are practice fields and so are
+// left out.
+func (f *FMS) qualQuads() []string {
+	fields := []int{}
+	for _, field := range f.c.Fields {
+		if !field.AutoMap {
+			fields = append(fields, field.ID)
+		}
+	}
+	sort.Ints(fields)
+
+	out := []string{}
+	for _, id := range fields {
+		for _, color := range []string{"red", "blue", "green", "yellow"} {
+			out = append(out, fmt.Sprintf("field%d:%s", id, color))
+		}
+	}
+	return out
+}
+
+func (f *FMS) apiGetQualSchedule(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(f.quals.Get())
+}
+
+func (f *FMS) apiGenerateQualSchedule(w http.ResponseWriter, r *http.Request) {
+	p := schedule.Params{}
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The roster and fields always come from the config so that
+	// the schedule can't name teams or quads that don't exist.
+	p.Teams = []int{}
+	for _, t := range f.c.SortedTeams() {
+		p.Teams = append(p.Teams, t.Number)
+	}
+	p.Quads = f.qualQuads()
+	if p.Seed == 0 {
+		p.Seed = time.Now().UnixNano()
+	}
+
+	sch, err := schedule.Generate(p)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := f.quals.Replace(sch); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+	f.l.Info("Qualification schedule generated", "matches", len(sch.Matches), "seed", p.Seed)
+	f.es.PublishActionComplete("Schedule Generation")
+}
+
+func (f *FMS) apiGetQualScheduleCSV(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"schedule.csv\"")
+	if err := f.quals.Get().WriteCSV(w); err != nil {
+		f.l.Warn("Error writing schedule CSV", "error", err)
+	}
+}
+
+func (f *FMS) apiStageQualMatch(w http.ResponseWriter, r *http.Request) {
+	number, err := strconv.Atoi(chi.URLParam(r, "number"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m, err := f.quals.Match(number)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := f.stageMapping(stagedMatch{Number: m.Number}, m.Mapping); err != nil {
+		f.l.Error("Error staging scheduled match", "match", m.Number, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.es.PublishActionComplete(fmt.Sprintf("Match %d Staged", m.Number))
+}
+
+func (f *FMS) uiViewQualSchedule(w http.ResponseWriter, r *http.Request) {
+	sch := f.quals.Get()
+
+	played := make(map[int]bool)
+	if records, err := f.archive.List(); err == nil {
+		for _, rec := range records {
+			played[rec.Number] = true
+		}
+	}
+
+	rows := make([]qualRow, len(sch.Matches))
+	for i, m := range sch.Matches {
+		byQuad := make(map[string]int, len(m.Mapping))
+		for team, quad := range m.Mapping {
+			byQuad[quad] = team
+		}
+		rows[i] = qualRow{Number: m.Number, Played: played[m.Number]}
+		for _, quad := range sch.Params.Quads {
+			rows[i].Teams = append(rows[i].Teams, byQuad[quad])
+		}
+	}
+
+	ctx := pongo2.Context{
+		"schedule": sch,
+		"rows":     rows,
+		"teams":    len(f.c.Teams),
+		"quads":    len(f.qualQuads()),
+	}
+	if staged, ok := f.staged(); ok {
+		ctx["staged"] = staged.Number
+	}
+	f.doTemplate(w, r, "views/admin/schedule.p2", ctx)
+}
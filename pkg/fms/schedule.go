@@ -0,0 +1,424 @@
+package fms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+)
+
+// The schedule integration follows a schedule published by some other
+// server, such as a league's scheduling system or a spreadsheet
+// behind a small script.  The server is polled for a JSON document
+// describing the current and next match, and the fields are remapped
+// whenever the current match changes.  If matches are staged, the
+// next match is staged as soon as it is known and committed when it
+// becomes the current match.
+//
+// Selectors are dotted paths into the document, for example
+// "data.matches.0" or "$.current".  The current and next selectors
+// locate each match, and the remaining selectors are relative to the
+// match or to each team within it.  With the default selectors the
+// server should return:
+//
+//	{
+//	  "current": {"number": 12, "teams": [{"team": 1234, "field": 1, "quadrant": "red"}, ...]},
+//	  "next":    {"number": 13, "teams": [...]}
+//	}
+
+const (
+	scheduleRate    = time.Second
+	scheduleTimeout = time.Second * 5
+)
+
+var (
+	errScheduleNoPath = errors.New("selector did not match")
+)
+
+func init() {
+	registerIntegration(&integration{
+		IntegrationInfo: config.IntegrationInfo{
+			ID:          config.IntegrationSchedule,
+			Slug:        "schedule",
+			Name:        "Remote Schedule",
+			Description: "Polls a server for the current and next match and remaps the fields whenever the current match changes.  Selectors are dotted paths into the JSON the server returns.",
+			Settings: []config.IntegrationSetting{
+				{Key: "url", Label: "Schedule URL", Type: config.SettingText, Required: true},
+				{Key: "token", Label: "Bearer Token", Type: config.SettingPassword},
+				{Key: "interval", Label: "Poll Interval (Seconds)", Type: config.SettingText, Default: "5", Required: true},
+				{Key: "stage", Label: "Stage Next Match", Type: config.SettingBool, Default: "false"},
+				{Key: "current", Label: "Current Match Selector", Type: config.SettingText, Default: "current"},
+				{Key: "next", Label: "Next Match Selector", Type: config.SettingText, Default: "next"},
+				{Key: "number", Label: "Match Number Selector", Type: config.SettingText, Default: "number"},
+				{Key: "teams", Label: "Teams Selector", Type: config.SettingText, Default: "teams", Required: true},
+				{Key: "team", Label: "Team Number Selector", Type: config.SettingText, Default: "team", Required: true},
+				{Key: "field", Label: "Field Selector", Type: config.SettingText, Default: "field", Required: true},
+				{Key: "quadrant", Label: "Quadrant Selector", Type: config.SettingText, Default: "quadrant", Required: true},
+			},
+		},
+		Init: func(f *FMS) { f.schedule = new(schedulePoller) },
+		Run:  (*FMS).doScheduleUpkeep,
+		UI: func(f *FMS, r chi.Router) {
+			r.Get("/", f.uiViewSchedule)
+		},
+	})
+}
+
+// scheduledMatch is a match as described by the remote schedule.
+type scheduledMatch struct {
+	Number  int
+	Mapping map[int]string
+}
+
+// schedulePoller remembers what the schedule looked like the last
+// time it was polled, so that the fields are only remapped when the
+// schedule changes and not every time it is polled.
+type schedulePoller struct {
+	mutex sync.RWMutex
+
+	// primed is set once the poller has worked out what the
+	// fields were doing before it first polled.
+	primed  bool
+	current *scheduledMatch
+	next    *scheduledMatch
+
+	status scheduleStatus
+}
+
+// scheduleStatus is shown on the integration's page.
+type scheduleStatus struct {
+	LastPoll    time.Time
+	LastError   string
+	LastApplied time.Time
+	Current     *scheduledMatch
+	Next        *scheduledMatch
+}
+
+type scheduleSelectors struct {
+	Current  string
+	Next     string
+	Number   string
+	Teams    string
+	Team     string
+	Field    string
+	Quadrant string
+}
+
+func (f *FMS) scheduleSetting(key string) string {
+	return f.c.IntegrationSetting(config.IntegrationSchedule, key)
+}
+
+func (f *FMS) scheduleSelectors() scheduleSelectors {
+	return scheduleSelectors{
+		Current:  f.scheduleSetting("current"),
+		Next:     f.scheduleSetting("next"),
+		Number:   f.scheduleSetting("number"),
+		Teams:    f.scheduleSetting("teams"),
+		Team:     f.scheduleSetting("team"),
+		Field:    f.scheduleSetting("field"),
+		Quadrant: f.scheduleSetting("quadrant"),
+	}
+}
+
+// selectPath walks a decoded JSON document along a dotted path.
+// Array elements are selected by index, either as "teams.0" or
+// "teams[0]", and a leading "$" is accepted so that JSONPath style
+// selectors work.  An empty path selects the document itself.
+func selectPath(doc interface{}, path string) (interface{}, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	if path == "" {
+		return doc, nil
+	}
+
+	cur := doc
+	for _, part := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]interface{}:
+			next, ok := v[part]
+			if !ok {
+				return nil, errScheduleNoPath
+			}
+			cur = next
+		case []interface{}:
+			idx, err := strconv.Atoi(part)
+			if err != nil || idx < 0 || idx >= len(v) {
+				return nil, errScheduleNoPath
+			}
+			cur = v[idx]
+		default:
+			return nil, errScheduleNoPath
+		}
+	}
+	return cur, nil
+}
+
+// scheduleInt converts a number that may have been sent as a string,
+// and allows a prefix so that fields may be named "field1".
+func scheduleInt(v interface{}, prefix string) (int, error) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), nil
+	case string:
+		return strconv.Atoi(strings.TrimPrefix(strings.ToLower(n), prefix))
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
+
+// match extracts the match at root from the document.  It returns
+// nil if the schedule doesn't have a match there, which is normal for
+// the next match at the end of the day.
+func (s scheduleSelectors) match(doc interface{}, root string, quads []string) (*scheduledMatch, error) {
+	if root == "" {
+		return nil, nil
+	}
+	m, err := selectPath(doc, root)
+	if errors.Is(err, errScheduleNoPath) || m == nil {
+		return nil, nil
+	}
+
+	out := &scheduledMatch{Mapping: make(map[int]string)}
+	if s.Number != "" {
+		if n, err := selectPath(m, s.Number); err == nil && n != nil {
+			if out.Number, err = scheduleInt(n, ""); err != nil {
+				return nil, fmt.Errorf("match number: %w", err)
+			}
+		}
+	}
+
+	tv, err := selectPath(m, s.Teams)
+	if err != nil {
+		return nil, fmt.Errorf("teams: %w", err)
+	}
+	teams, ok := tv.([]interface{})
+	if !ok {
+		return nil, errors.New("teams is not a list")
+	}
+
+	for _, t := range teams {
+		nv, err := selectPath(t, s.Team)
+		if err != nil {
+			return nil, fmt.Errorf("team number: %w", err)
+		}
+		team, err := scheduleInt(nv, "")
+		if err != nil {
+			return nil, fmt.Errorf("team number: %w", err)
+		}
+		if team == 0 {
+			continue
+		}
+
+		fv, err := selectPath(t, s.Field)
+		if err != nil {
+			return nil, fmt.Errorf("team %d field: %w", team, err)
+		}
+		field, err := scheduleInt(fv, "field")
+		if err != nil {
+			return nil, fmt.Errorf("team %d field: %w", team, err)
+		}
+
+		qv, err := selectPath(t, s.Quadrant)
+		if err != nil {
+			return nil, fmt.Errorf("team %d quadrant: %w", team, err)
+		}
+		quad := fmt.Sprintf("field%d:%s", field, strings.ToLower(fmt.Sprint(qv)))
+		if !isQuad(quads, quad) {
+			return nil, fmt.Errorf("team %d is scheduled for %s, which does not exist", team, quad)
+		}
+		out.Mapping[team] = quad
+	}
+	return out, nil
+}
+
+func isQuad(quads []string, quad string) bool {
+	for _, q := range quads {
+		if q == quad {
+			return true
+		}
+	}
+	return false
+}
+
+func sameMatch(a, b *scheduledMatch) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Number != b.Number || len(a.Mapping) != len(b.Mapping) {
+		return false
+	}
+	for team, quad := range a.Mapping {
+		if b.Mapping[team] != quad {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *FMS) doScheduleUpkeep() {
+	ticker := time.NewTicker(scheduleRate)
+	cl := &http.Client{Timeout: scheduleTimeout}
+	var lastPoll time.Time
+
+	for {
+		select {
+		case <-f.stop:
+			ticker.Stop()
+			return
+		case <-ticker.C:
+			if !f.c.Integrations.Enabled(config.IntegrationSchedule) {
+				continue
+			}
+			interval, err := strconv.Atoi(f.scheduleSetting("interval"))
+			if err != nil || interval < 1 {
+				interval = 1
+			}
+			if time.Since(lastPoll) < time.Second*time.Duration(interval) {
+				continue
+			}
+			lastPoll = time.Now()
+			f.pollSchedule(cl)
+		}
+	}
+}
+
+func (f *FMS) fetchSchedule(cl *http.Client) (interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, f.scheduleSetting("url"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token := f.scheduleSetting("token"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := cl.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("schedule server returned %s", resp.Status)
+	}
+
+	var doc interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (f *FMS) pollSchedule(cl *http.Client) {
+	p := f.schedule
+	err := f.followSchedule(cl)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.status.LastPoll = time.Now()
+	if err == nil {
+		p.status.LastError = ""
+		return
+	}
+	// Only complain when something new goes wrong, since the
+	// server is polled every few seconds.
+	if p.status.LastError != err.Error() {
+		f.l.Warn("Could not follow remote schedule", "error", err)
+		f.es.PublishError(err)
+	}
+	p.status.LastError = err.Error()
+}
+
+// followSchedule polls the schedule and applies any changes to it.
+func (f *FMS) followSchedule(cl *http.Client) error {
+	doc, err := f.fetchSchedule(cl)
+	if err != nil {
+		return err
+	}
+
+	sel := f.scheduleSelectors()
+	current, err := sel.match(doc, sel.Current, f.quads)
+	if err != nil {
+		return fmt.Errorf("current match: %w", err)
+	}
+	next, err := sel.match(doc, sel.Next, f.quads)
+	if err != nil {
+		return fmt.Errorf("next match: %w", err)
+	}
+
+	p := f.schedule
+	p.mutex.Lock()
+	if !p.primed {
+		// Whatever is already on the fields is taken to be
+		// what the schedule said last, so that restarting the
+		// FMS doesn't remap the fields.
+		if rec, err := f.archive.Current(); err == nil {
+			p.current = &scheduledMatch{Number: rec.Number, Mapping: rec.Mapping}
+		}
+		if staged, ok := f.staged(); ok {
+			m, _ := f.tlm.GetStageMapping()
+			p.next = &scheduledMatch{Number: staged.Number, Mapping: m}
+		}
+		p.primed = true
+	}
+	currentChanged := current != nil && len(current.Mapping) > 0 && !sameMatch(current, p.current)
+	nextChanged := next != nil && len(next.Mapping) > 0 && !sameMatch(next, p.next)
+	p.status.Current = current
+	p.status.Next = next
+	p.mutex.Unlock()
+
+	stage := f.c.IntegrationSettingBool(config.IntegrationSchedule, "stage")
+
+	if currentChanged {
+		// If the match that is staged is the one that is now
+		// current it is committed, otherwise the fields are
+		// remapped directly.
+		staged, ok := f.staged()
+		stageMap, _ := f.tlm.GetStageMapping()
+		if stage && ok && sameMatch(&scheduledMatch{Number: staged.Number, Mapping: stageMap}, current) {
+			if err := f.commitStagedMap(); err != nil {
+				return err
+			}
+		} else if err := f.applyMapping(current.Number, current.Mapping); err != nil {
+			return err
+		}
+		f.l.Info("Remapped field from remote schedule", "match", current.Number)
+		f.scheduleApplied(func(p *schedulePoller) { p.current = current })
+	}
+
+	if stage && nextChanged {
+		if err := f.stageMapping(stagedMatch{Number: next.Number}, next.Mapping); err != nil {
+			return err
+		}
+		f.l.Info("Staged match from remote schedule", "match", next.Number)
+		f.scheduleApplied(func(p *schedulePoller) { p.next = next })
+	}
+	return nil
+}
+
+func (f *FMS) scheduleApplied(fn func(*schedulePoller)) {
+	f.schedule.mutex.Lock()
+	defer f.schedule.mutex.Unlock()
+	fn(f.schedule)
+	f.schedule.status.LastApplied = time.Now()
+}
+
+func (f *FMS) uiViewSchedule(w http.ResponseWriter, r *http.Request) {
+	f.schedule.mutex.RLock()
+	status := f.schedule.status
+	f.schedule.mutex.RUnlock()
+
+	ctx := pongo2.Context{
+		"status":  status,
+		"url":     f.scheduleSetting("url"),
+		"enabled": f.c.Integrations.Enabled(config.IntegrationSchedule),
+	}
+	f.doTemplate(w, r, "views/integrations/schedule.p2", ctx)
+}
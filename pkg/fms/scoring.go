@@ -0,0 +1,371 @@
+package fms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+	"github.com/gizmo-platform/gizmo/pkg/match"
+	"github.com/gizmo-platform/gizmo/pkg/scoring"
+)
+
+// Small scrimmages often don't have a scoring system, so the FMS can
+// keep score itself.  Scores are entered for each quadrant of a match
+// record and stored with the record, which means the teams are always
+// the ones the TLM had mapped.  Results and rankings are computed
+// whenever they are asked for so that they always reflect the current
+// scoring elements.
+
+const (
+	scoreSheetName = "score.json"
+)
+
+// scoreRow is a single quadrant of a score sheet as shown on the
+// results pages.
+type scoreRow struct {
+	*scoring.Entry
+
+	Quad string
+	Name string
+}
+
+type scoreView struct {
+	Sheet *scoring.Sheet
+	Rows  []scoreRow
+}
+
+type rankingView struct {
+	scoring.Ranking
+
+	Name string
+}
+
+// requireScoring rejects requests while scoring is disabled.
+func (f *FMS) requireScoring(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !f.c.ScoringEnabled {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte("Scoring is not enabled!"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (f *FMS) teamName(team int) string {
+	if t, ok := f.c.Teams[team]; ok {
+		return t.Name
+	}
+	return ""
+}
+
+// scoreSheet loads the score sheet for a record.  Records that haven't
+// been scored yet get an empty sheet.
+func (f *FMS) scoreSheet(rec match.Record) (*scoring.Sheet, bool, error) {
+	src, err := f.archive.OpenArtifact(rec.ID, scoreSheetName)
+	if errors.Is(err, match.ErrNoSuchArtifact) {
+		s := scoring.NewSheet(rec)
+		s.Tally(f.c.ScoringElements)
+		return s, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer src.Close()
+
+	s := new(scoring.Sheet)
+	if err := json.NewDecoder(src).Decode(s); err != nil {
+		return nil, false, err
+	}
+	s.Tally(f.c.ScoringElements)
+	return s, true, nil
+}
+
+// scoreSheets returns the sheet for every record that has been
+// scored, oldest first.
+func (f *FMS) scoreSheets() ([]*scoring.Sheet, error) {
+	records, err := f.archive.List()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []*scoring.Sheet{}
+	for _, rec := range records {
+		s, scored, err := f.scoreSheet(rec)
+		if err != nil {
+			f.l.Warn("Skipping unreadable score sheet", "record", rec.ID, "error", err)
+			continue
+		}
+		if scored {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (f *FMS) scoreView(s *scoring.Sheet) scoreView {
+	v := scoreView{Sheet: s}
+	for quad, e := range s.Entries {
+		v.Rows = append(v.Rows, scoreRow{Entry: e, Quad: quad, Name: f.teamName(e.Team)})
+	}
+	sort.Slice(v.Rows, func(i, j int) bool { return v.Rows[i].Quad < v.Rows[j].Quad })
+	return v
+}
+
+// qualSheets drops sheets for matches that aren't in the
+// qualification schedule.  If no schedule has been generated every
+// numbered match is a qualification match.
+func (f *FMS) qualSheets(sheets []*scoring.Sheet) []*scoring.Sheet {
+	sch := f.quals.Get()
+	if len(sch.Matches) == 0 {
+		return sheets
+	}
+
+	scheduled := make(map[int]bool, len(sch.Matches))
+	for _, m := range sch.Matches {
+		scheduled[m.Number] = true
+	}
+	out := []*scoring.Sheet{}
+	for _, s := range sheets {
+		if scheduled[s.Number] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (f *FMS) rankingViews(sheets []*scoring.Sheet) []rankingView {
+	rankings := scoring.Rank(f.qualSheets(sheets))
+	out := make([]rankingView, len(rankings))
+	for i, r := range rankings {
+		out[i] = rankingView{Ranking: r, Name: f.teamName(r.Team)}
+	}
+	return out
+}
+
+func (f *FMS) apiGetScoreResults(w http.ResponseWriter, r *http.Request) {
+	sheets, err := f.scoreSheets()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(sheets)
+}
+
+func (f *FMS) apiGetScoreRankings(w http.ResponseWriter, r *http.Request) {
+	sheets, err := f.scoreSheets()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(scoring.Rank(f.qualSheets(sheets)))
+}
+
+// scoreUpdate is the body of a request to score a match.  Counts are
+// keyed by quad and then by element:
+//
+//	{"Number": 3, "Counts": {"field1:red": {"barrels": 3, "penalty": 1}, ...}}
+//
+// Quadrants that aren't included keep the counts they already had.
+// Matches that were mapped by hand don't have a number, so the
+// scorekeeper may provide one so that the match counts towards the
+// rankings.
+type scoreUpdate struct {
+	Number *int
+	Counts map[string]map[string]int
+}
+
+func (f *FMS) apiUpdateScore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	update := new(scoreUpdate)
+	if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rec, err := f.archive.Get(id)
+	if errors.Is(err, match.ErrNoSuchRecord) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s, _, err := f.scoreSheet(rec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	known := make(map[string]bool, len(f.c.ScoringElements))
+	for _, el := range f.c.ScoringElements {
+		known[el.Key] = true
+	}
+	if update.Number != nil {
+		if *update.Number < 0 {
+			http.Error(w, "match numbers cannot be negative", http.StatusBadRequest)
+			return
+		}
+		s.Number = *update.Number
+	}
+	for quad, c := range update.Counts {
+		e, ok := s.Entries[quad]
+		if !ok {
+			http.Error(w, fmt.Sprintf("no team was mapped to %s in this match", quad), http.StatusBadRequest)
+			return
+		}
+		for key, n := range c {
+			if !known[key] {
+				http.Error(w, "unknown scoring element: "+key, http.StatusBadRequest)
+				return
+			}
+			if n < 0 {
+				http.Error(w, "counts cannot be negative", http.StatusBadRequest)
+				return
+			}
+			e.Counts[key] = n
+		}
+	}
+	s.Updated = time.Now()
+
+	buf, err := json.Marshal(s)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := f.archive.WriteArtifact(rec.ID, scoreSheetName, buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+	f.l.Info("Score saved", "record", rec.ID, "number", s.Number)
+	f.es.PublishActionComplete("Score Save")
+}
+
+func (f *FMS) apiUpdateScoring(w http.ResponseWriter, r *http.Request) {
+	cTmp := new(config.FMSConfig)
+
+	if err := json.NewDecoder(r.Body).Decode(&cTmp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	seen := make(map[string]bool, len(cTmp.ScoringElements))
+	for _, el := range cTmp.ScoringElements {
+		if el.Key == "" || el.Name == "" {
+			http.Error(w, "scoring elements must have a key and a name", http.StatusBadRequest)
+			return
+		}
+		if seen[el.Key] {
+			http.Error(w, "duplicate scoring element: "+el.Key, http.StatusBadRequest)
+			return
+		}
+		seen[el.Key] = true
+	}
+
+	// We do this rather than deserializing into the main config
+	// struct to ensure that its not possible to rewrite other
+	// unrelated parts of the config via this API.
+	f.c.ScoringEnabled = cTmp.ScoringEnabled
+	f.c.ScoringElements = cTmp.ScoringElements
+
+	if err := f.c.Save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+	f.es.PublishActionComplete("Configuration Save")
+}
+
+func (f *FMS) uiViewScoringSetup(w http.ResponseWriter, r *http.Request) {
+	f.doTemplate(w, r, "views/setup/scoring.p2", pongo2.Context{"cfg": f.c})
+}
+
+func (f *FMS) uiViewScoreList(w http.ResponseWriter, r *http.Request) {
+	records, err := f.archive.List()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		f.doTemplate(w, r, "errors/internal.p2", pongo2.Context{"error": err})
+		return
+	}
+
+	views := []scoreView{}
+	for i := len(records) - 1; i >= 0; i-- {
+		s, _, err := f.scoreSheet(records[i])
+		if err != nil {
+			f.l.Warn("Skipping unreadable score sheet", "record", records[i].ID, "error", err)
+			continue
+		}
+		views = append(views, f.scoreView(s))
+	}
+	f.doTemplate(w, r, "views/scoring/list.p2", pongo2.Context{"sheets": views})
+}
+
+func (f *FMS) uiViewScoreEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		f.doTemplate(w, r, "errors/internal.p2", pongo2.Context{"error": err})
+		return
+	}
+
+	rec, err := f.archive.Get(id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		f.doTemplate(w, r, "errors/internal.p2", pongo2.Context{"error": err})
+		return
+	}
+
+	s, _, err := f.scoreSheet(rec)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		f.doTemplate(w, r, "errors/internal.p2", pongo2.Context{"error": err})
+		return
+	}
+
+	ctx := pongo2.Context{
+		"sheet":    f.scoreView(s),
+		"elements": f.c.ScoringElements,
+	}
+	f.doTemplate(w, r, "views/scoring/entry.p2", ctx)
+}
+
+func (f *FMS) uiViewScoreResults(w http.ResponseWriter, r *http.Request) {
+	sheets, err := f.scoreSheets()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		f.doTemplate(w, r, "errors/internal.p2", pongo2.Context{"error": err})
+		return
+	}
+
+	views := make([]scoreView, len(sheets))
+	for i, s := range sheets {
+		views[len(views)-1-i] = f.scoreView(s)
+	}
+	f.doTemplate(w, r, "views/scoring/results.p2", pongo2.Context{"sheets": views})
+}
+
+func (f *FMS) uiViewScoreRankings(w http.ResponseWriter, r *http.Request) {
+	sheets, err := f.scoreSheets()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		f.doTemplate(w, r, "errors/internal.p2", pongo2.Context{"error": err})
+		return
+	}
+	f.doTemplate(w, r, "views/scoring/rankings.p2", pongo2.Context{"rankings": f.rankingViews(sheets)})
+}
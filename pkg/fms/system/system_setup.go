@@ -37,6 +37,7 @@ const (
 	grafanaPromSrc   = "/usr/share/grafana/conf/provisioning/datasources/default.yaml"
 	grafanaDashCfg   = "/usr/share/grafana/conf/provisioning/dashboards/default.yaml"
 	grafanaDashGizmo = "/var/lib/grafana/dashboards/gizmo.json"
+	grafanaDashFMS   = "/var/lib/grafana/dashboards/fms.json"
 	grafanaDashHome  = "/usr/share/grafana/public/dashboards/home.json"
 	grafanaDashLand  = "/var/lib/grafana/dashboards/home.json"
 
@@ -308,6 +309,10 @@ func (st *SetupTool) configureGrafana() error {
 		return err
 	}
 
+	if err := st.sc.Template(grafanaDashFMS, "tpl/grafana_dash_fms.json.tpl", 0644, nil); err != nil {
+		return err
+	}
+
 	if err := st.sc.Template(grafanaDashHome, "tpl/grafana_dash_home.json.tpl", 0644, nil); err != nil {
 		return err
 	}
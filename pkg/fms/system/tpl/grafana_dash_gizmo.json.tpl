@@ -720,6 +720,33 @@
   "tags": [],
   "templating": {
     "list": [
+      {
+        "current": {
+          "selected": true,
+          "text": "All",
+          "value": "$__all"
+        },
+        "datasource": {
+          "type": "prometheus",
+          "uid": "PBFA97CFB590B2093"
+        },
+        "definition": "label_values(gizmo_robot_rssi, field)",
+        "hide": 0,
+        "includeAll": true,
+        "label": "Field",
+        "multi": false,
+        "name": "field",
+        "options": [],
+        "query": {
+          "query": "label_values(gizmo_robot_rssi, field)",
+          "refId": "PrometheusVariableQueryEditor-VariableQuery"
+        },
+        "refresh": 1,
+        "regex": "",
+        "skipUrlSync": false,
+        "sort": 1,
+        "type": "query"
+      },
       {
         "current": {
           "selected": false,
@@ -730,7 +757,7 @@
           "type": "prometheus",
           "uid": "PBFA97CFB590B2093"
         },
-        "definition": "label_values(team)",
+        "definition": "label_values(gizmo_robot_rssi{field=~\"$field\"}, team)",
         "hide": 0,
         "includeAll": false,
         "label": "Team",
@@ -738,7 +765,7 @@
         "name": "team",
         "options": [],
         "query": {
-          "query": "label_values(team)",
+          "query": "label_values(gizmo_robot_rssi{field=~\"$field\"}, team)",
           "refId": "PrometheusVariableQueryEditor-VariableQuery"
         },
         "refresh": 1,
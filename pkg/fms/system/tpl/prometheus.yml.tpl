@@ -6,6 +6,9 @@ scrape_configs:
   - job_name: prometheus
     static_configs:
       - targets: ["localhost:9090"]
+  - job_name: fms
+    static_configs:
+      - targets: ["localhost:8080"]
   - job_name: gizmo
     http_sd_configs:
       - url: http://localhost:8080/metrics-sd
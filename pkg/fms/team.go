@@ -0,0 +1,85 @@
+package fms
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+)
+
+const (
+	teamTelemetryWindow = time.Minute * 5
+)
+
+// teamStatus is a read-only view of everything the FMS knows about a
+// single team.  It is safe to show to anyone, and so must never
+// contain network credentials.
+type teamStatus struct {
+	Number int
+	Name   string
+
+	// Current is the quad the team is mapped to right now, and
+	// Next is the quad they are staged to, if any.  Actual is the
+	// quad that their driver's station is physically plugged
+	// into.
+	Current string
+	Next    string
+	Actual  string
+
+	GizmoConnected  bool
+	GizmoFirmwareOK bool
+	GizmoHardwareOK bool
+	GizmoMeta       config.GizmoMeta
+	DSConnected     bool
+	DSBootOK        bool
+	DSVersionOK     bool
+	DSMeta          config.DSMeta
+
+	Telemetry []telemetrySample
+}
+
+// teamStatus assembles the status for a single team.  Telemetry is
+// best effort and an error retrieving it will not prevent the rest of
+// the status from being returned.
+func (f *FMS) teamStatus(team int) (teamStatus, error) {
+	t, ok := f.c.Teams[team]
+	if !ok {
+		return teamStatus{}, errors.New("no such team")
+	}
+
+	ts := teamStatus{
+		Number: team,
+		Name:   t.Name,
+	}
+
+	if current, err := f.tlm.GetCurrentMapping(); err == nil {
+		ts.Current = current[team]
+	}
+	if stage, err := f.tlm.GetStageMapping(); err == nil {
+		ts.Next = stage[team]
+	}
+
+	ts.Actual = f.actualQuadForTeam(team)
+
+	f.connectedMutex.RLock()
+	_, ts.GizmoConnected = f.connectedGizmo[team]
+	_, ts.DSConnected = f.connectedDS[team]
+	f.connectedMutex.RUnlock()
+
+	f.metaMutex.RLock()
+	ts.GizmoMeta = f.gizmoMeta[team]
+	ts.GizmoHardwareOK = ts.GizmoMeta.HWVersionOK(f.c.CompatHardwareVersions)
+	ts.GizmoFirmwareOK = ts.GizmoMeta.FWVersionOK(f.c.CompatFirmwareVersions)
+	ts.DSMeta = f.dsMeta[team]
+	ts.DSVersionOK = ts.DSMeta.VersionOK(f.c.CompatDSVersions)
+	ts.DSBootOK = ts.DSMeta.BootmodeOK(f.c.CompatDSBootmodes)
+	f.metaMutex.RUnlock()
+
+	samples, err := f.recentTelemetry(team, teamTelemetryWindow)
+	if err != nil {
+		f.l.Debug("Could not retrieve telemetry", "team", team, "error", err)
+	}
+	ts.Telemetry = samples
+
+	return ts, nil
+}
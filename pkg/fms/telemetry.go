@@ -0,0 +1,134 @@
+package fms
+
+import (
+	"time"
+
+	"github.com/gizmo-platform/gizmo/pkg/metrics"
+)
+
+// The FMS keeps a short rolling history of the telemetry forwarded by
+// each driver's station so that volunteers can see what a robot has
+// been doing without needing to open Grafana.  Reports arrive several
+// times a second, so they are downsampled into fixed steps, keeping
+// the most recent report in each step.
+
+const (
+	// telemetryStep is the resolution of the history.
+	telemetryStep = time.Second * 5
+
+	// telemetryHistory is how far back the history goes.
+	telemetryHistory = time.Minute * 10
+
+	// hudTelemetryWindow is how much history is drawn on the
+	// HUD, which has much less room than the team page.
+	hudTelemetryWindow = time.Minute * 2
+)
+
+// telemetrySample is a single point in time view of the values a
+// robot reports about itself.
+type telemetrySample struct {
+	Time            time.Time
+	BatteryVoltage  float64
+	RSSI            float64
+	WifiReconnects  int
+	ControlFrameAge float64
+	ControlLoss     float64
+	ControlRTT      float64
+
+	PwrBoard  bool
+	PwrPico   bool
+	PwrGPIO   bool
+	PwrServo  bool
+	PwrMainA  bool
+	PwrMainB  bool
+	PwrPixels bool
+}
+
+func newTelemetrySample(s metrics.Sample) telemetrySample {
+	return telemetrySample{
+		Time:            s.Time,
+		BatteryVoltage:  s.Report.Voltage(),
+		RSSI:            float64(s.Report.RSSI),
+		WifiReconnects:  int(s.Report.WifiReconnects),
+		ControlFrameAge: float64(s.Report.ControlFrameAge) / 1000,
+		ControlLoss:     s.Link.Loss,
+		ControlRTT:      s.Link.RTT,
+		PwrBoard:        s.Report.PwrBoard,
+		PwrPico:         s.Report.PwrPico,
+		PwrGPIO:         s.Report.PwrGPIO,
+		PwrServo:        s.Report.PwrServo,
+		PwrMainA:        s.Report.PwrMainA,
+		PwrMainB:        s.Report.PwrMainB,
+		PwrPixels:       s.Report.PwrPixels,
+	}
+}
+
+// recordTelemetry adds a sample to the team's history.  The caller
+// must hold the robotTelemetryMutex.
+func (f *FMS) recordTelemetry(team int, s metrics.Sample) {
+	sample := newTelemetrySample(s)
+	sample.Time = s.Time.Truncate(telemetryStep)
+
+	h := f.telemetryHistory[team]
+	switch {
+	case len(h) > 0 && h[len(h)-1].Time.Equal(sample.Time):
+		h[len(h)-1] = sample
+	case len(h) > 0 && h[len(h)-1].Time.After(sample.Time):
+		// Late arrivals are dropped rather than reordering
+		// the history.
+		return
+	default:
+		h = append(h, sample)
+	}
+
+	cutoff := time.Now().Add(-telemetryHistory)
+	first := 0
+	for first < len(h) && h[first].Time.Before(cutoff) {
+		first++
+	}
+	f.telemetryHistory[team] = h[first:]
+}
+
+// expireTelemetryHistory drops the history for teams that haven't
+// reported at all within the history window.
+func (f *FMS) expireTelemetryHistory() {
+	cutoff := time.Now().Add(-telemetryHistory)
+
+	f.robotTelemetryMutex.Lock()
+	for team, h := range f.telemetryHistory {
+		if len(h) == 0 || h[len(h)-1].Time.Before(cutoff) {
+			delete(f.telemetryHistory, team)
+		}
+	}
+	f.robotTelemetryMutex.Unlock()
+}
+
+// latestTelemetry returns the most recent sample for every team that
+// has reported recently.
+func (f *FMS) latestTelemetry() (map[int]telemetrySample, error) {
+	f.robotTelemetryMutex.RLock()
+	defer f.robotTelemetryMutex.RUnlock()
+
+	out := make(map[int]telemetrySample, len(f.robotTelemetry))
+	for team, s := range f.robotTelemetry {
+		out[team] = newTelemetrySample(s)
+	}
+	return out, nil
+}
+
+// recentTelemetry returns the samples for the given team over the
+// window that ends now.  Samples are returned oldest first.
+func (f *FMS) recentTelemetry(team int, window time.Duration) ([]telemetrySample, error) {
+	cutoff := time.Now().Add(-window)
+
+	f.robotTelemetryMutex.RLock()
+	defer f.robotTelemetryMutex.RUnlock()
+
+	out := []telemetrySample{}
+	for _, s := range f.telemetryHistory[team] {
+		if !s.Time.Before(cutoff) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
@@ -4,16 +4,24 @@
 package fms
 
 import (
+	"io"
 	nhttp "net/http"
 	"sync"
 	"time"
 
 	"github.com/flosch/pongo2/v6"
 	"github.com/hashicorp/go-hclog"
+	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/gizmo-platform/gizmo/pkg/bracket"
 	"github.com/gizmo-platform/gizmo/pkg/config"
 	"github.com/gizmo-platform/gizmo/pkg/http"
+	"github.com/gizmo-platform/gizmo/pkg/inspection"
+	"github.com/gizmo-platform/gizmo/pkg/match"
+	"github.com/gizmo-platform/gizmo/pkg/metrics"
+	"github.com/gizmo-platform/gizmo/pkg/practice"
 	"github.com/gizmo-platform/gizmo/pkg/routeros/netinstall"
+	"github.com/gizmo-platform/gizmo/pkg/schedule"
 )
 
 // TeamLocationMapper looks at all teams trying to fetch a value and
@@ -42,6 +50,8 @@ type EventStreamer interface {
 	PublishError(error)
 	PublishFileFetch(string)
 	PublishLogLine(string)
+	PublishAlert(string, int, string, string)
+	PublishRobotState(int, string)
 }
 
 // FileFetcher fetches restricted files that cannot be baked into the
@@ -66,6 +76,54 @@ type NetController interface {
 	BootstrapPhase3() error
 }
 
+// MatchArchive keeps a durable record of every match that is run so
+// that events which happen during the match can be reviewed later.
+type MatchArchive interface {
+	Begin(int, string, map[int]string) error
+	Log(match.Event) error
+	Current() (match.Record, error)
+	Get(int) (match.Record, error)
+	List() ([]match.Record, error)
+	Start() error
+	Stop() error
+
+	AppendArtifact(int, string, []byte, []byte) error
+	WriteArtifact(int, string, []byte) error
+	OpenArtifact(int, string) (io.ReadCloser, error)
+}
+
+// ScheduleStore holds the qualification schedule.
+type ScheduleStore interface {
+	Get() schedule.Schedule
+	Replace(schedule.Schedule) error
+	Match(int) (schedule.Match, error)
+}
+
+// BracketStore holds the elimination bracket.
+type BracketStore interface {
+	Get() bracket.Bracket
+	Replace(bracket.Bracket) error
+	Record(int, [2]int) (bracket.Bracket, error)
+}
+
+// PracticeStore holds the practice field bookings.
+type PracticeStore interface {
+	List() []practice.Booking
+	Book(practice.Booking) (practice.Booking, error)
+	Cancel(int) (practice.Booking, error)
+	Due(time.Time) ([]practice.Booking, []practice.Booking)
+	Mark([]practice.Booking, []practice.Booking, []practice.Booking) error
+	Active() map[string]int
+}
+
+// InspectionStore holds the results of robot inspections.
+type InspectionStore interface {
+	Add(inspection.Record) error
+	History(int) []inspection.Record
+	Latest() map[int]inspection.Record
+	Passed(int) bool
+}
+
 // FMS encapsulates the FMS runnable.
 type FMS struct {
 	s  *http.Server
@@ -75,12 +133,20 @@ type FMS struct {
 
 	fetcher FileFetcher
 
-	tlm TeamLocationMapper
-	net NetController
+	tlm         TeamLocationMapper
+	net         NetController
+	archive     MatchArchive
+	quals       ScheduleStore
+	bracket     BracketStore
+	practice    PracticeStore
+	inspections InspectionStore
 
 	swg *sync.WaitGroup
 	tpl *pongo2.TemplateSet
 
+	promRegistry *prometheus.Registry
+	metrics      *fmsMetrics
+
 	quads []string
 
 	stop           chan struct{}
@@ -92,6 +158,28 @@ type FMS struct {
 	metaMutex      *sync.RWMutex
 	dsPresent      map[string]int
 	dsPresentMutex *sync.RWMutex
+	alerts         map[string]*alert
+	alertsPending  map[string]time.Time
+	alertMutex     *sync.RWMutex
+
+	robotTelemetry      map[int]metrics.Sample
+	telemetryHistory    map[int][]telemetrySample
+	robotTelemetryMutex *sync.RWMutex
+
+	stagedMatch *stagedMatch
+	stagedMutex *sync.RWMutex
+	mapMutex    *sync.Mutex
+
+	schedule *schedulePoller
+
+	portalSessions  map[string]portalSession
+	portalMutex     *sync.RWMutex
+	connectionLog   map[int][]connectionEvent
+	connectionMutex *sync.RWMutex
+
+	webhookQueue chan *webhookDelivery
+	webhookLog   []*webhookDelivery
+	webhookMutex *sync.RWMutex
 
 	netinst *netinstall.Installer
 }
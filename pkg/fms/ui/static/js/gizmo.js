@@ -4,6 +4,8 @@ const MsgTypeLogLine = 2;
 const MsgTypeActionStart = 3;
 const MsgTypeActionComplete = 4;
 const MsgTypeFileFetch = 5;
+const MsgTypeAlert = 6;
+const MsgTypeRobotState = 7;
 
 var ws = new ReconnectingWebSocket('ws://' + document.location.host + '/api/eventstream');
 
@@ -52,6 +54,25 @@ ws.addEventListener("message", (event) => {
                 close: true
             }).showToast();
             break;
+        case MsgTypeAlert:
+            console.log("alert", msg.State, msg.ID, msg.Message);
+            if (msg.State == "raised") {
+                Toastify({
+                    text: "Alert: " + msg.Message,
+                    duration: -1,
+                    close: true
+                }).showToast();
+            }
+            break;
+        case MsgTypeRobotState:
+            console.log("robot", msg.Team, msg.State);
+            if (msg.State == "offline") {
+                Toastify({
+                    text: "Robot " + msg.Team + " has stopped reporting",
+                    duration: 5000
+                }).showToast();
+            }
+            break;
         }
 
     } catch (error) {
@@ -0,0 +1,31 @@
+// Draws a simple line chart as an SVG string.  This is intentionally
+// tiny so that displays don't need to load a charting library just to
+// show a trend.
+function sparkline(values, opts) {
+    opts = opts || {};
+    const width = opts.width || 300;
+    const height = opts.height || 60;
+    const stroke = opts.stroke || 'currentColor';
+
+    if (values.length == 0) {
+        return '<svg class="sparkline" viewBox="0 0 ' + width + ' ' + height + '"></svg>';
+    }
+
+    let lo = (opts.min !== undefined) ? opts.min : Math.min(...values);
+    let hi = (opts.max !== undefined) ? opts.max : Math.max(...values);
+    if (hi == lo) {
+        hi = lo + 1;
+    }
+
+    const step = values.length > 1 ? width / (values.length - 1) : 0;
+    const points = values.map((v, i) => {
+        const clamped = Math.min(Math.max(v, lo), hi);
+        const x = i * step;
+        const y = height - ((clamped - lo) / (hi - lo)) * height;
+        return x.toFixed(1) + ',' + y.toFixed(1);
+    });
+
+    return '<svg class="sparkline" viewBox="0 0 ' + width + ' ' + height + '" preserveAspectRatio="none">' +
+        '<polyline fill="none" stroke="' + stroke + '" stroke-width="2" points="' + points.join(' ') + '" />' +
+        '</svg>';
+}
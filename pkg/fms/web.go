@@ -2,16 +2,19 @@ package fms
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 
 	"github.com/gizmo-platform/gizmo/pkg/config"
+	"github.com/gizmo-platform/gizmo/pkg/match"
 	"github.com/gizmo-platform/gizmo/pkg/routeros/netinstall"
 	"github.com/gizmo-platform/gizmo/pkg/util"
 )
@@ -60,7 +63,7 @@ func (f *FMS) apiUpdateStageMap(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := f.tlm.InsertStageMapping(mapping); err != nil {
+	if err := f.stageMapping(stagedMatch{}, mapping); err != nil {
 		f.l.Error("Error remapping teams!", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprintf(w, "Error inserting map: %s", err)
@@ -70,7 +73,7 @@ func (f *FMS) apiUpdateStageMap(w http.ResponseWriter, r *http.Request) {
 }
 
 func (f *FMS) apiCommitStageMap(w http.ResponseWriter, r *http.Request) {
-	if err := f.tlm.CommitStagedMap(); err != nil {
+	if err := f.commitStagedMap(); err != nil {
 		f.l.Error("Error commiting staged mapping!", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprintf(w, "Error commiting staged map: %s", err)
@@ -89,7 +92,7 @@ func (f *FMS) apiUpdateMapImmediate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := f.tlm.InsertOnDemandMap(mapping); err != nil {
+	if err := f.applyMapping(0, mapping); err != nil {
 		f.l.Error("Error remapping teams!", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprintf(w, "Error inserting map: %s", err)
@@ -250,6 +253,33 @@ func (f *FMS) apiUpdateCompatVer(w http.ResponseWriter, r *http.Request) {
 	f.es.PublishActionComplete("Configuration Save")
 }
 
+func (f *FMS) apiUpdateAlertThresholds(w http.ResponseWriter, r *http.Request) {
+	cTmp := new(config.FMSConfig)
+
+	if err := json.NewDecoder(r.Body).Decode(&cTmp); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// We do this rather than deserializing into the main config
+	// struct to ensure that its not possible to rewrite other
+	// unrelated parts of the config via this API.
+	if cTmp.AlertBatteryVoltage == nil || cTmp.AlertRSSI == nil || cTmp.AlertControlFrameAge == nil {
+		http.Error(w, "all alert thresholds must be set", http.StatusBadRequest)
+		return
+	}
+	f.c.AlertBatteryVoltage = cTmp.AlertBatteryVoltage
+	f.c.AlertRSSI = cTmp.AlertRSSI
+	f.c.AlertControlFrameAge = cTmp.AlertControlFrameAge
+
+	if err := f.c.Save(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+	f.es.PublishActionComplete("Configuration Save")
+}
+
 func (f *FMS) apiFieldAdd(w http.ResponseWriter, r *http.Request) {
 	field := new(config.Field)
 
@@ -361,6 +391,7 @@ func (f *FMS) apiBootstrapBeginPhase0(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	f.notifyWebhooks(webhookBootstrapPhase, webhookBootstrap{0})
 }
 
 func (f *FMS) apiBootstrapBeginPhase1(w http.ResponseWriter, r *http.Request) {
@@ -368,6 +399,7 @@ func (f *FMS) apiBootstrapBeginPhase1(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	f.notifyWebhooks(webhookBootstrapPhase, webhookBootstrap{1})
 }
 
 func (f *FMS) apiBootstrapBeginPhase2(w http.ResponseWriter, r *http.Request) {
@@ -375,6 +407,7 @@ func (f *FMS) apiBootstrapBeginPhase2(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	f.notifyWebhooks(webhookBootstrapPhase, webhookBootstrap{2})
 }
 
 func (f *FMS) apiBootstrapBeginPhase3(w http.ResponseWriter, r *http.Request) {
@@ -382,6 +415,7 @@ func (f *FMS) apiBootstrapBeginPhase3(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	f.notifyWebhooks(webhookBootstrapPhase, webhookBootstrap{3})
 }
 
 func (f *FMS) apiNetReconcile(w http.ResponseWriter, r *http.Request) {
@@ -422,56 +456,110 @@ func (f *FMS) apiZapController(w http.ResponseWriter, r *http.Request) {
 }
 
 func (f *FMS) apiFieldHUD(w http.ResponseWriter, r *http.Request) {
-	type hudQuad struct {
-		Color           string
-		Actual          int
-		Team            int
-		GizmoConnected  bool
-		GizmoFirmwareOK bool
-		GizmoHardwareOK bool
-		GizmoMeta       config.GizmoMeta
-		DSConnected     bool
-		DSBootOK        bool
-		DSVersionOK     bool
-		DSMeta          config.DSMeta
+	json.NewEncoder(w).Encode(f.hudModel())
+}
+
+func (f *FMS) apiGetTeamStatus(w http.ResponseWriter, r *http.Request) {
+	team, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	f.dsPresentMutex.RLock()
-	defer f.dsPresentMutex.RUnlock()
-	m, _ := f.tlm.GetCurrentMapping()
-	tm := f.invertTLMMap(m)
-
-	out := make([][]hudQuad, len(f.c.Fields))
-	for _, field := range f.quads {
-		parts := strings.Split(field, ":")
-		n, err := strconv.Atoi(strings.TrimPrefix(parts[0], "field"))
-		if err != nil {
-			f.l.Error("Error decoding field number", "error", err)
-			continue
-		}
-		n = n - 1
-		team := tm[field]
+	ts, err := f.teamStatus(team)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(ts)
+}
 
-		fTmp := hudQuad{
-			Color:  parts[1],
-			Team:   team,
-			Actual: f.dsPresent[field],
+func (f *FMS) apiGetTeamTelemetry(w http.ResponseWriter, r *http.Request) {
+	team, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	window := telemetryHistory
+	if mStr := r.URL.Query().Get("minutes"); mStr != "" {
+		minutes, err := strconv.Atoi(mStr)
+		if err != nil || minutes <= 0 {
+			http.Error(w, "minutes must be a positive number", http.StatusBadRequest)
+			return
 		}
-		f.connectedMutex.RLock()
-		_, fTmp.GizmoConnected = f.connectedGizmo[team]
-		_, fTmp.DSConnected = f.connectedDS[team]
-		f.connectedMutex.RUnlock()
-
-		f.metaMutex.RLock()
-		fTmp.GizmoMeta = f.gizmoMeta[team]
-		fTmp.GizmoHardwareOK = fTmp.GizmoMeta.HWVersionOK(f.c.CompatHardwareVersions)
-		fTmp.GizmoFirmwareOK = fTmp.GizmoMeta.FWVersionOK(f.c.CompatFirmwareVersions)
-		fTmp.DSMeta = f.dsMeta[team]
-		fTmp.DSVersionOK = fTmp.DSMeta.VersionOK(f.c.CompatDSVersions)
-		fTmp.DSBootOK = fTmp.DSMeta.BootmodeOK(f.c.CompatDSBootmodes)
-		f.metaMutex.RUnlock()
-
-		out[n] = append(out[n], fTmp)
-	}
-	json.NewEncoder(w).Encode(out)
+		window = min(time.Minute*time.Duration(minutes), telemetryHistory)
+	}
+
+	samples, _ := f.recentTelemetry(team, window)
+	json.NewEncoder(w).Encode(samples)
+}
+
+func (f *FMS) apiGetAlerts(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(f.activeAlerts())
+}
+
+func (f *FMS) apiAckAlert(w http.ResponseWriter, r *http.Request) {
+	if err := f.ackAlert(chi.URLParam(r, "id")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+}
+
+func (f *FMS) apiGetQuadCorrection(w http.ResponseWriter, r *http.Request) {
+	team, err := strconv.Atoi(chi.URLParam(r, "team"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	qc, err := f.quadCorrectionFor(team)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(qc)
+}
+
+func (f *FMS) apiApplyQuadCorrection(w http.ResponseWriter, r *http.Request) {
+	team, err := strconv.Atoi(chi.URLParam(r, "team"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := f.remapToActual(team); err != nil {
+		f.l.Error("Error correcting team quad", "team", team, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (f *FMS) apiGetMatchTelemetry(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	team, err := strconv.Atoi(chi.URLParam(r, "team"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name := TelemetryCSVName(team)
+	src, err := f.archive.OpenArtifact(id, name)
+	if errors.Is(err, match.ErrNoSuchArtifact) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer src.Close()
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"match-%05d-%s\"", id, name))
+	io.Copy(w, src)
 }
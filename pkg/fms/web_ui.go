@@ -2,11 +2,14 @@ package fms
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/flosch/pongo2/v6"
+	"github.com/go-chi/chi/v5"
+	"rsc.io/qr"
 )
 
 func (f *FMS) uiViewLanding(w http.ResponseWriter, r *http.Request) {
@@ -72,6 +75,9 @@ func (f *FMS) uiViewStageMap(w http.ResponseWriter, r *http.Request) {
 		"roster":   f.c.SortedTeams(),
 		"quadJSON": string(out),
 	}
+	if staged, ok := f.staged(); ok {
+		ctx["staged"] = staged
+	}
 
 	f.doTemplate(w, r, "views/map/stage.p2", ctx)
 }
@@ -93,7 +99,7 @@ func (f *FMS) uiViewUpdateStageMap(w http.ResponseWriter, r *http.Request) {
 		m[tNum] = position
 	}
 
-	if err := f.tlm.InsertStageMapping(m); err != nil {
+	if err := f.stageMapping(stagedMatch{}, m); err != nil {
 		f.l.Error("Error remapping teams!", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprintf(w, "Error inserting map: %s", err)
@@ -103,13 +109,12 @@ func (f *FMS) uiViewUpdateStageMap(w http.ResponseWriter, r *http.Request) {
 }
 
 func (f *FMS) uiViewCommitStageMap(w http.ResponseWriter, r *http.Request) {
-	if err := f.tlm.CommitStagedMap(); err != nil {
+	if err := f.commitStagedMap(); err != nil {
 		f.l.Error("Error commiting staged mapping!", "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprintf(w, "Error commiting staged map: %s", err)
 		return
 	}
-	f.tlm.InsertStageMapping(nil)
 	http.Redirect(w, r, "/ui/admin/map/stage", http.StatusSeeOther)
 }
 
@@ -134,7 +139,7 @@ func (f *FMS) uiViewNetAdvanced(w http.ResponseWriter, r *http.Request) {
 }
 
 func (f *FMS) uiViewIntegrations(w http.ResponseWriter, r *http.Request) {
-	f.doTemplate(w, r, "views/setup/integrations.p2", pongo2.Context{"cfg": f.c})
+	f.doTemplate(w, r, "views/setup/integrations.p2", pongo2.Context{"integrations": f.integrationViews()})
 }
 
 func (f *FMS) uiViewFlashDevice(w http.ResponseWriter, r *http.Request) {
@@ -152,3 +157,71 @@ func (f *FMS) uiViewNetReconcile(w http.ResponseWriter, r *http.Request) {
 func (f *FMS) uiViewCompatCheck(w http.ResponseWriter, r *http.Request) {
 	f.doTemplate(w, r, "views/setup/compat-check.p2", pongo2.Context{"cfg": f.c})
 }
+
+func (f *FMS) uiViewAlertThresholds(w http.ResponseWriter, r *http.Request) {
+	f.doTemplate(w, r, "views/setup/alerts.p2", pongo2.Context{"cfg": f.c})
+}
+
+func (f *FMS) uiViewAlerts(w http.ResponseWriter, r *http.Request) {
+	ctx := pongo2.Context{"alerts": f.activeAlerts()}
+	if rec, err := f.archive.Current(); err == nil {
+		ctx["record"] = rec
+	}
+	f.doTemplate(w, r, "views/admin/alerts.p2", ctx)
+}
+
+func (f *FMS) uiViewMatchList(w http.ResponseWriter, r *http.Request) {
+	records, err := f.archive.List()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		f.doTemplate(w, r, "errors/internal.p2", pongo2.Context{"error": err})
+		return
+	}
+
+	// Newest matches are the most interesting, so they go first.
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	f.doTemplate(w, r, "views/admin/matches.p2", pongo2.Context{"records": records})
+}
+
+func (f *FMS) uiViewTeamList(w http.ResponseWriter, r *http.Request) {
+	f.doTemplate(w, r, "views/team/list.p2", pongo2.Context{"roster": f.c.SortedTeams()})
+}
+
+func (f *FMS) uiViewTeamStatus(w http.ResponseWriter, r *http.Request) {
+	team, err := strconv.Atoi(chi.URLParam(r, "number"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		f.doTemplate(w, r, "errors/internal.p2", pongo2.Context{"error": err})
+		return
+	}
+
+	t, ok := f.c.Teams[team]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		f.doTemplate(w, r, "errors/internal.p2", pongo2.Context{"error": errors.New("no such team")})
+		return
+	}
+
+	f.doTemplate(w, r, "views/team/status.p2", pongo2.Context{"number": team, "name": t.Name})
+}
+
+func (f *FMS) uiViewTeamQR(w http.ResponseWriter, r *http.Request) {
+	team, err := strconv.Atoi(chi.URLParam(r, "number"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// The QR code points at whatever address the requester used
+	// to reach the FMS, which is usually the infrastructure
+	// network address when printed from the pits.
+	code, err := qr.Encode(fmt.Sprintf("http://%s/ui/team/%d", r.Host, team), qr.M)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(code.PNG())
+}
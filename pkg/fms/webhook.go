@@ -0,0 +1,401 @@
+package fms
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/flosch/pongo2/v6"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+)
+
+// Webhooks notify other systems of things that happen on the field.
+// Each event is POSTed as JSON to every configured webhook that wants
+// it:
+//
+//	{"ID": "<delivery>", "Event": "map-committed", "Time": "...", "Data": {...}}
+//
+// Requests carry the event name in X-Gizmo-Event and the delivery ID
+// in X-Gizmo-Delivery.  If the webhook has a secret, the body is
+// signed with HMAC-SHA256 and the hex digest is sent in
+// X-Gizmo-Signature as "sha256=<digest>".  Deliveries that fail are
+// retried with backoff for a while before being given up on, and the
+// outcome of recent deliveries is kept so that it can be reviewed
+// from the setup page.
+
+const (
+	webhookWorkers    = 4
+	webhookQueueLen   = 64
+	webhookLogLen     = 100
+	webhookTimeout    = time.Second * 5
+	webhookMaxElapsed = time.Minute
+
+	webhookMapCommitted     = "map-committed"
+	webhookMatchStarted     = "match-started"
+	webhookMatchStopped     = "match-stopped"
+	webhookTeamConnected    = "team-connected"
+	webhookTeamDisconnected = "team-disconnected"
+	webhookRobotOffline     = "robot-offline"
+	webhookAlertRaised      = "alert-raised"
+	webhookBootstrapPhase   = "bootstrap-complete"
+	webhookTest             = "test"
+
+	webhookStatePending   = "pending"
+	webhookStateDelivered = "delivered"
+	webhookStateFailed    = "failed"
+	webhookStateDropped   = "dropped"
+
+	deviceDS    = "ds"
+	deviceGizmo = "gizmo"
+)
+
+// webhookEvents are the events that a webhook may subscribe to.  The
+// test event is always delivered when requested and so isn't listed.
+var webhookEvents = []string{
+	webhookMapCommitted,
+	webhookMatchStarted,
+	webhookMatchStopped,
+	webhookTeamConnected,
+	webhookTeamDisconnected,
+	webhookRobotOffline,
+	webhookAlertRaised,
+	webhookBootstrapPhase,
+}
+
+func init() {
+	registerIntegration(&integration{
+		IntegrationInfo: config.IntegrationInfo{
+			ID:          config.IntegrationWebhooks,
+			Slug:        "webhooks",
+			Name:        "Webhooks",
+			Description: "Sends an HTTP POST to other systems whenever something happens on the field that they have asked to hear about.",
+		},
+		Init: func(f *FMS) {
+			f.webhookQueue = make(chan *webhookDelivery, webhookQueueLen)
+			f.webhookMutex = new(sync.RWMutex)
+		},
+		Run: (*FMS).doWebhookUpkeep,
+		API: func(f *FMS, r chi.Router) {
+			r.Post("/", f.apiWebhookAdd)
+			r.Delete("/{id}", f.apiWebhookDelete)
+			r.Post("/{id}/test", f.apiWebhookTest)
+		},
+		UI: func(f *FMS, r chi.Router) {
+			r.Get("/", f.uiViewWebhooks)
+		},
+	})
+}
+
+type webhookPayload struct {
+	ID    string
+	Event string
+	Time  time.Time
+	Data  interface{}
+}
+
+type webhookMatch struct {
+	ID      int
+	Number  int
+	Name    string
+	Mapping map[int]string
+}
+
+type webhookTeam struct {
+	Team   int
+	Device string
+}
+
+type webhookBootstrap struct {
+	Phase int
+}
+
+// webhookDelivery is a single event being sent to a single webhook.
+type webhookDelivery struct {
+	ID       string
+	Webhook  string
+	URL      string
+	Event    string
+	Queued   time.Time
+	Finished time.Time
+	Attempts int
+	Status   int
+	State    string
+	Error    string
+
+	secret string
+	body   []byte
+}
+
+// webhookStatusError is returned for responses that weren't
+// successful.
+type webhookStatusError int
+
+func (e webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook returned %d %s", int(e), http.StatusText(int(e)))
+}
+
+func validWebhookEvent(event string) bool {
+	for _, e := range webhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// signWebhook computes the signature header value for a body.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notifyWebhooks queues an event for every webhook that wants it.  It
+// never blocks; if the queue is full the delivery is dropped and
+// recorded as such.
+func (f *FMS) notifyWebhooks(event string, data interface{}) {
+	if !f.c.Integrations.Enabled(config.IntegrationWebhooks) {
+		return
+	}
+
+	for _, wh := range f.webhooks() {
+		if !wh.Wants(event) {
+			continue
+		}
+		f.queueWebhook(wh, event, data)
+	}
+}
+
+// webhooks returns a copy of the configured webhooks.  The list in the
+// config must only be touched with the webhook lock held since events
+// are sent from many places at once.
+func (f *FMS) webhooks() []*config.Webhook {
+	f.webhookMutex.RLock()
+	defer f.webhookMutex.RUnlock()
+	return append([]*config.Webhook{}, f.c.Webhooks...)
+}
+
+func (f *FMS) queueWebhook(wh *config.Webhook, event string, data interface{}) {
+	d := &webhookDelivery{
+		ID:      uuid.New().String(),
+		Webhook: wh.ID,
+		URL:     wh.URL,
+		Event:   event,
+		Queued:  time.Now(),
+		State:   webhookStatePending,
+		secret:  wh.Secret,
+	}
+
+	body, err := json.Marshal(webhookPayload{
+		ID:    d.ID,
+		Event: event,
+		Time:  d.Queued,
+		Data:  data,
+	})
+	if err != nil {
+		f.l.Warn("Could not marshal webhook payload", "event", event, "error", err)
+		return
+	}
+	d.body = body
+
+	f.webhookMutex.Lock()
+	f.webhookLog = append(f.webhookLog, d)
+	if len(f.webhookLog) > webhookLogLen {
+		f.webhookLog = f.webhookLog[len(f.webhookLog)-webhookLogLen:]
+	}
+	f.webhookMutex.Unlock()
+
+	select {
+	case f.webhookQueue <- d:
+	default:
+		f.l.Warn("Webhook queue is full, dropping delivery", "event", event, "url", wh.URL)
+		f.finishWebhook(d, 0, webhookStateDropped, "queue full")
+	}
+}
+
+// doWebhookUpkeep runs the workers that deliver webhooks until the
+// FMS is stopped.
+func (f *FMS) doWebhookUpkeep() {
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < webhookWorkers; i++ {
+		go f.webhookWorker(ctx)
+	}
+	<-f.stop
+	cancel()
+}
+
+func (f *FMS) webhookWorker(ctx context.Context) {
+	cl := &http.Client{Timeout: webhookTimeout}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d := <-f.webhookQueue:
+			f.deliverWebhook(ctx, cl, d)
+		}
+	}
+}
+
+func (f *FMS) deliverWebhook(ctx context.Context, cl *http.Client, d *webhookDelivery) {
+	status := 0
+	attempt := func() error {
+		f.webhookMutex.Lock()
+		d.Attempts++
+		f.webhookMutex.Unlock()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.body))
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Gizmo-Event", d.Event)
+		req.Header.Set("X-Gizmo-Delivery", d.ID)
+		if d.secret != "" {
+			req.Header.Set("X-Gizmo-Signature", signWebhook(d.secret, d.body))
+		}
+
+		resp, err := cl.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		status = resp.StatusCode
+
+		switch {
+		case status >= 200 && status < 300:
+			return nil
+		case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests, status >= 500:
+			return webhookStatusError(status)
+		default:
+			// The receiver didn't like what it got, and
+			// sending it again won't change its mind.
+			return backoff.Permanent(webhookStatusError(status))
+		}
+	}
+
+	bo := backoff.NewExponentialBackOff(backoff.WithMaxElapsedTime(webhookMaxElapsed))
+	if err := backoff.Retry(attempt, backoff.WithContext(bo, ctx)); err != nil {
+		f.l.Warn("Webhook delivery failed", "event", d.Event, "url", d.URL, "error", err)
+		f.finishWebhook(d, status, webhookStateFailed, err.Error())
+		return
+	}
+	f.l.Debug("Webhook delivered", "event", d.Event, "url", d.URL)
+	f.finishWebhook(d, status, webhookStateDelivered, "")
+}
+
+func (f *FMS) finishWebhook(d *webhookDelivery, status int, state, msg string) {
+	f.webhookMutex.Lock()
+	d.Status = status
+	d.State = state
+	d.Error = msg
+	d.Finished = time.Now()
+	f.webhookMutex.Unlock()
+}
+
+// webhookDeliveries returns the recent deliveries, newest first.
+func (f *FMS) webhookDeliveries() []webhookDelivery {
+	f.webhookMutex.RLock()
+	defer f.webhookMutex.RUnlock()
+
+	out := make([]webhookDelivery, len(f.webhookLog))
+	for i, d := range f.webhookLog {
+		out[len(out)-1-i] = *d
+	}
+	return out
+}
+
+func (f *FMS) apiWebhookAdd(w http.ResponseWriter, r *http.Request) {
+	wh := new(config.Webhook)
+
+	if err := json.NewDecoder(r.Body).Decode(wh); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "webhook URL must be an http or https URL", http.StatusBadRequest)
+		return
+	}
+	for _, e := range wh.Events {
+		if !validWebhookEvent(e) {
+			http.Error(w, "unknown event: "+e, http.StatusBadRequest)
+			return
+		}
+	}
+
+	wh.ID = uuid.New().String()
+	f.webhookMutex.Lock()
+	f.c.Webhooks = append(f.c.Webhooks, wh)
+	err = f.c.Save()
+	f.webhookMutex.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+
+	f.es.PublishActionComplete("Configuration Save")
+}
+
+func (f *FMS) apiWebhookDelete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	f.webhookMutex.Lock()
+	hooks := []*config.Webhook{}
+	for _, wh := range f.c.Webhooks {
+		if wh.ID != id {
+			hooks = append(hooks, wh)
+		}
+	}
+	f.c.Webhooks = hooks
+	err := f.c.Save()
+	f.webhookMutex.Unlock()
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		f.es.PublishError(err)
+		return
+	}
+
+	f.es.PublishActionComplete("Configuration Save")
+}
+
+func (f *FMS) apiWebhookTest(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if !f.c.Integrations.Enabled(config.IntegrationWebhooks) {
+		http.Error(w, "the webhook integration is not enabled", http.StatusConflict)
+		return
+	}
+
+	for _, wh := range f.webhooks() {
+		if wh.ID == id {
+			f.queueWebhook(wh, webhookTest, nil)
+			return
+		}
+	}
+	http.Error(w, "no webhook with that ID exists", http.StatusNotFound)
+}
+
+func (f *FMS) uiViewWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx := pongo2.Context{
+		"webhooks":   f.webhooks(),
+		"events":     webhookEvents,
+		"enabled":    f.c.Integrations.Enabled(config.IntegrationWebhooks),
+		"deliveries": f.webhookDeliveries(),
+	}
+	f.doTemplate(w, r, "views/integrations/webhooks.p2", ctx)
+}
@@ -0,0 +1,19 @@
+package inspection
+
+import (
+	"github.com/hashicorp/go-hclog"
+)
+
+// WithLogger configures the logger for the store.
+func WithLogger(l hclog.Logger) Option {
+	return func(s *Store) {
+		s.l = l.Named("inspection")
+	}
+}
+
+// WithFile sets the file that the records are saved to.
+func WithFile(p string) Option {
+	return func(s *Store) {
+		s.path = p
+	}
+}
@@ -0,0 +1,110 @@
+// Package inspection keeps the results of robot inspections.
+package inspection
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/gizmo-platform/gizmo/pkg/util"
+)
+
+// New returns a store configured with the given options.
+func New(opts ...Option) *Store {
+	s := new(Store)
+	s.l = hclog.NewNullLogger()
+	s.path = "inspection.json"
+	s.records = make(map[int][]Record)
+
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Load reads the records back from disk.  It is not an error for
+// there to be no records yet.
+func (s *Store) Load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	records := make(map[int][]Record)
+	if err := json.NewDecoder(f).Decode(&records); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.records = records
+	s.mutex.Unlock()
+	s.l.Info("Inspections loaded", "teams", len(records))
+	return nil
+}
+
+// Add records an inspection and saves it.  The time is filled in if
+// it isn't set.
+func (s *Store) Add(rec Record) error {
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	records := make(map[int][]Record, len(s.records)+1)
+	for team, history := range s.records {
+		records[team] = history
+	}
+	records[rec.Team] = append(append([]Record{}, s.records[rec.Team]...), rec)
+
+	if err := s.save(records); err != nil {
+		return err
+	}
+	s.records = records
+	s.l.Info("Inspection recorded", "team", rec.Team, "inspector", rec.Inspector, "passed", rec.Passed)
+	return nil
+}
+
+// History returns every inspection of a team, oldest first.
+func (s *Store) History(team int) []Record {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return append([]Record{}, s.records[team]...)
+}
+
+// Latest returns the most recent inspection of every team that has
+// been inspected.
+func (s *Store) Latest() map[int]Record {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	out := make(map[int]Record, len(s.records))
+	for team, history := range s.records {
+		if len(history) > 0 {
+			out[team] = history[len(history)-1]
+		}
+	}
+	return out
+}
+
+// Passed returns true if the most recent inspection of the team
+// passed.
+func (s *Store) Passed(team int) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	history := s.records[team]
+	return len(history) > 0 && history[len(history)-1].Passed
+}
+
+func (s *Store) save(records map[int][]Record) error {
+	return util.WriteJSONFile(s.path, records)
+}
@@ -0,0 +1,42 @@
+package inspection
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Store keeps the inspection records for every team so that they
+// survive restarts of the FMS.
+type Store struct {
+	l hclog.Logger
+
+	path string
+
+	mutex   sync.RWMutex
+	records map[int][]Record
+}
+
+// Option configures the Store.
+type Option func(*Store)
+
+// Record is the result of inspecting a team's robot.  A team may be
+// inspected any number of times, and only the most recent record
+// counts.
+type Record struct {
+	Team      int
+	Inspector string
+	Time      time.Time
+	Passed    bool
+	Notes     string
+
+	// Items holds whether each item on the checklist was
+	// checked, keyed by the item's key.
+	Items map[string]bool
+
+	// HardwareVersion and FirmwareVersion are what the team's
+	// Gizmo last reported when the inspection was recorded.
+	HardwareVersion string
+	FirmwareVersion string
+}
@@ -0,0 +1,372 @@
+// Package match maintains the archive of matches that have been run
+// on the fields so that what happened during them can be reviewed
+// after the event.
+package match
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/gizmo-platform/gizmo/pkg/util"
+)
+
+const (
+	recordFile = "record.json"
+)
+
+var (
+	// ErrNoMatch is returned when an event is logged before any
+	// match has begun.
+	ErrNoMatch = errors.New("no match is in progress")
+
+	// ErrMatchStarted is returned when a match is started more
+	// than once.
+	ErrMatchStarted = errors.New("the match has already started")
+
+	// ErrMatchNotRunning is returned when a match is stopped that
+	// isn't running.
+	ErrMatchNotRunning = errors.New("the match is not running")
+
+	// ErrNoSuchRecord is returned when a record is requested that
+	// is not in the archive.
+	ErrNoSuchRecord = errors.New("no record with that ID exists")
+
+	// ErrNoSuchArtifact is returned when an artifact is requested
+	// that is not stored with the record.
+	ErrNoSuchArtifact = errors.New("no artifact with that name exists")
+
+	// ErrBadArtifactName is returned for artifact names that would
+	// escape the record directory or replace the record itself.
+	ErrBadArtifactName = errors.New("artifact names must be plain file names")
+)
+
+// New returns an archive configured with the given options.
+func New(opts ...Option) *Archive {
+	a := new(Archive)
+	a.l = hclog.NewNullLogger()
+	a.dir = "matches"
+
+	for _, o := range opts {
+		o(a)
+	}
+	return a
+}
+
+// Recover loads the most recent record from the archive so that
+// events continue to be appended to it across restarts.
+func (a *Archive) Recover() error {
+	ids, err := a.ids()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	r, err := a.load(ids[len(ids)-1])
+	if err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	a.current = r
+	a.mutex.Unlock()
+	return nil
+}
+
+// Begin starts a new record for a match with the given number, name,
+// and mapping.  The number may be zero if the match was not scheduled,
+// and the name is only set for bracket matches.
+func (a *Archive) Begin(number int, name string, mapping map[int]string) error {
+	// The lock is held while the ID is allocated so that two
+	// matches beginning at once can't both take the same one.
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	ids, err := a.ids()
+	if err != nil {
+		return err
+	}
+	id := 1
+	if len(ids) > 0 {
+		id = ids[len(ids)-1] + 1
+	}
+
+	m := make(map[int]string, len(mapping))
+	for team, quad := range mapping {
+		m[team] = quad
+	}
+
+	r := &Record{
+		ID:      id,
+		Number:  number,
+		Name:    name,
+		Mapping: m,
+		Mapped:  time.Now(),
+		Events:  []Event{},
+	}
+
+	// The record only becomes current once it is on disk, so a
+	// failed save can't leave a current match that the next one
+	// would take the ID of.
+	if err := a.save(r); err != nil {
+		return err
+	}
+	a.current = r
+	a.l.Info("Match record started", "id", id, "number", number, "name", name)
+	return nil
+}
+
+// Log appends an event to the current record.
+func (a *Archive) Log(e Event) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.current == nil {
+		return ErrNoMatch
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	a.current.Events = append(a.current.Events, e)
+	return a.save(a.current)
+}
+
+// Start marks the current match as started.
+func (a *Archive) Start() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.current == nil {
+		return ErrNoMatch
+	}
+	if !a.current.Started.IsZero() {
+		return ErrMatchStarted
+	}
+	a.current.Started = time.Now()
+	a.current.Events = append(a.current.Events, Event{Time: a.current.Started, Kind: "match-start"})
+	a.l.Info("Match started", "id", a.current.ID, "number", a.current.Number)
+	return a.save(a.current)
+}
+
+// Stop marks the current match as stopped.
+func (a *Archive) Stop() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.current == nil {
+		return ErrNoMatch
+	}
+	if !a.current.Running() {
+		return ErrMatchNotRunning
+	}
+	a.current.Stopped = time.Now()
+	a.current.Events = append(a.current.Events, Event{Time: a.current.Stopped, Kind: "match-stop"})
+	a.l.Info("Match stopped", "id", a.current.ID, "number", a.current.Number)
+	return a.save(a.current)
+}
+
+// Current returns a copy of the record for the match that is
+// currently in progress.
+func (a *Archive) Current() (Record, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	if a.current == nil {
+		return Record{}, ErrNoMatch
+	}
+	r := *a.current
+	r.Events = append([]Event{}, a.current.Events...)
+	return r, nil
+}
+
+// Get returns the record with the given ID.
+func (a *Archive) Get(id int) (Record, error) {
+	r, err := a.load(id)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Record{}, ErrNoSuchRecord
+		}
+		return Record{}, err
+	}
+	return *r, nil
+}
+
+// List returns every record in the archive, oldest first.
+func (a *Archive) List() ([]Record, error) {
+	ids, err := a.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	out := []Record{}
+	for _, id := range ids {
+		r, err := a.load(id)
+		if err != nil {
+			a.l.Warn("Skipping unreadable record", "id", id, "error", err)
+			continue
+		}
+		out = append(out, *r)
+	}
+	return out, nil
+}
+
+// AppendArtifact appends data to a file that is stored alongside the
+// record.  If the file doesn't exist yet, it is created and the header
+// is written first.
+func (a *Archive) AppendArtifact(id int, name string, header, data []byte) error {
+	if err := checkArtifactName(name); err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if err := os.MkdirAll(a.recordDir(id), 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(a.recordDir(id), name)
+	_, err := os.Stat(path)
+	isNew := errors.Is(err, os.ErrNotExist)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if isNew {
+		if _, err := f.Write(header); err != nil {
+			return err
+		}
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// WriteArtifact replaces a file that is stored alongside the record.
+// The file is written in full before it replaces the old one, so
+// readers never see it half written.
+func (a *Archive) WriteArtifact(id int, name string, data []byte) error {
+	if err := checkArtifactName(name); err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if err := os.MkdirAll(a.recordDir(id), 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(a.recordDir(id), name)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// OpenArtifact opens a file that is stored alongside the record.  The
+// caller must close it.
+func (a *Archive) OpenArtifact(id int, name string) (io.ReadCloser, error) {
+	if err := checkArtifactName(name); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(a.recordDir(id), name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSuchArtifact
+	}
+	return f, err
+}
+
+// Artifacts lists the files that are stored alongside the record.
+func (a *Archive) Artifacts(id int) ([]string, error) {
+	entries, err := os.ReadDir(a.recordDir(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoSuchRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := []string{}
+	for _, e := range entries {
+		// Files left over from a write that never finished
+		// aren't artifacts.
+		if e.IsDir() || e.Name() == recordFile || strings.HasSuffix(e.Name(), ".tmp") {
+			continue
+		}
+		out = append(out, e.Name())
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func checkArtifactName(name string) error {
+	if name == "" || name == recordFile || name != filepath.Base(name) || name == "." || name == ".." {
+		return ErrBadArtifactName
+	}
+	return nil
+}
+
+func (a *Archive) recordDir(id int) string {
+	return filepath.Join(a.dir, fmt.Sprintf("%05d", id))
+}
+
+func (a *Archive) ids() ([]int, error) {
+	entries, err := os.ReadDir(a.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []int{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := []int{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		id, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		out = append(out, id)
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+func (a *Archive) load(id int) (*Record, error) {
+	f, err := os.Open(filepath.Join(a.recordDir(id), recordFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := new(Record)
+	if err := json.NewDecoder(f).Decode(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (a *Archive) save(r *Record) error {
+	if err := os.MkdirAll(a.recordDir(r.ID), 0755); err != nil {
+		return err
+	}
+
+	return util.WriteJSONFile(filepath.Join(a.recordDir(r.ID), recordFile), r)
+}
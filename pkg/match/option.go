@@ -0,0 +1,20 @@
+package match
+
+import (
+	"github.com/hashicorp/go-hclog"
+)
+
+// WithLogger configures the logger for the archive.
+func WithLogger(l hclog.Logger) Option {
+	return func(a *Archive) {
+		a.l = l.Named("archive")
+	}
+}
+
+// WithDirectory sets the directory that match records are stored
+// within.
+func WithDirectory(d string) Option {
+	return func(a *Archive) {
+		a.dir = d
+	}
+}
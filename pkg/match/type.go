@@ -0,0 +1,65 @@
+package match
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Archive keeps a record of each match that is run on the fields.
+// Records are stored one per directory so that other artifacts
+// related to the match can be stored alongside them.
+type Archive struct {
+	l hclog.Logger
+
+	dir string
+
+	mutex   sync.RWMutex
+	current *Record
+}
+
+// Option configures the Archive.
+type Option func(*Archive)
+
+// Record contains everything that is known about a single match.  A
+// match begins whenever a new mapping is applied to the fields.
+type Record struct {
+	// ID is assigned sequentially by the archive and is unique
+	// for every record.
+	ID int
+
+	// Number is the match number assigned by whatever scheduled
+	// the match, if anything did.  Mappings applied by hand do
+	// not have a match number.
+	Number int
+
+	// Name is the name of the bracket match, such as "Final",
+	// for matches that were staged from the elimination bracket.
+	Name string
+
+	Mapping map[int]string
+	Mapped  time.Time
+
+	// Started and Stopped are set when whatever is running the
+	// match says that it has started and stopped.  They are zero
+	// if that hasn't happened, which is always the case for
+	// matches that aren't run by a scoring system.
+	Started time.Time
+	Stopped time.Time
+
+	Events []Event
+}
+
+// Running returns true if the match has started but not stopped.
+func (r Record) Running() bool {
+	return !r.Started.IsZero() && r.Stopped.IsZero()
+}
+
+// Event is something noteworthy that happened during a match.
+type Event struct {
+	Time    time.Time
+	Kind    string
+	Team    int
+	Message string
+}
@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Newer Gizmo firmware may send values beyond the ones that are part
+// of the fixed report.  These are carried in the Extra map of the
+// report and exported as gauges that are created as they are first
+// seen.  Since the names come from the robot, they are sanitized and
+// must match an allow-list before a gauge is created, which keeps a
+// misbehaving robot from creating unbounded numbers of metrics.
+
+const (
+	// ReportVersion is the newest version of the report schema
+	// that is understood.  Reports without a version are from
+	// firmware that predates the Extra map.
+	ReportVersion = 1
+
+	// maxExtraMetrics caps the number of dynamically created
+	// gauges regardless of the allow-list.
+	maxExtraMetrics = 32
+
+	// maxExtraNameLen is the longest name that will be accepted
+	// for an extra value.
+	maxExtraNameLen = 48
+
+	extraPrefix = "gizmo_robot_extra_"
+)
+
+// DefaultExtraAllowList contains the patterns for extra values that
+// are exported unless configured otherwise.  Patterns are matched
+// with path.Match against the sanitized name.
+var DefaultExtraAllowList = []string{
+	"motor_current_*",
+	"temperature_*",
+	"loop_time_seconds",
+}
+
+// ExtraValues returns the extra values from the report as numbers.
+// Booleans are converted to 0 or 1, and anything that is neither a
+// number nor a boolean is ignored.
+func (r Report) ExtraValues() map[string]float64 {
+	out := make(map[string]float64, len(r.Extra))
+	for k, v := range r.Extra {
+		switch val := v.(type) {
+		case float64:
+			out[k] = val
+		case bool:
+			out[k] = fCast(val)
+		}
+	}
+	return out
+}
+
+// ExtraMetrics returns the extra values from the report keyed by the
+// name of the metric they are exported as.  Values that aren't
+// permitted are dropped, and where several names sanitize to the same
+// metric only the first in sorted order is kept.  No more than
+// maxExtraMetrics are returned, so a single report can't create
+// unbounded numbers of series.
+func (r Report) ExtraMetrics(allow []string) map[string]float64 {
+	vals := r.ExtraValues()
+	names := make([]string, 0, len(vals))
+	for name := range vals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make(map[string]float64)
+	for _, name := range names {
+		metric, ok := ExtraMetricName(name, allow)
+		if !ok {
+			continue
+		}
+		if _, dup := out[metric]; dup {
+			continue
+		}
+		if len(out) >= maxExtraMetrics {
+			break
+		}
+		out[metric] = vals[name]
+	}
+	return out
+}
+
+// ExtraMetricName sanitizes the name of an extra value and returns
+// the name of the metric it is exported as.  The second return value
+// is false if the name is unusable or not permitted by the
+// allow-list.
+func ExtraMetricName(name string, allow []string) (string, bool) {
+	name = strings.ToLower(name)
+	if name == "" || len(name) > maxExtraNameLen {
+		return "", false
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z':
+		case c == '_' || (c >= '0' && c <= '9'):
+			if i == 0 {
+				return "", false
+			}
+		default:
+			return "", false
+		}
+	}
+
+	for _, pattern := range allow {
+		if ok, _ := path.Match(pattern, name); ok {
+			return extraPrefix + name, true
+		}
+	}
+	return "", false
+}
+
+// observeExtras sets the gauges for any extra values in the report,
+// creating them if required.
+func (m *Metrics) observeExtras(teamNum string, r Report) {
+	for name, val := range r.ExtraValues() {
+		metric, ok := ExtraMetricName(name, m.extraAllow)
+		if !ok {
+			m.l.Trace("Ignoring extra value", "team", teamNum, "name", name)
+			continue
+		}
+
+		g := m.extraGauge(metric)
+		if g == nil {
+			continue
+		}
+		g.With(prometheus.Labels{"team": teamNum}).Set(val)
+	}
+}
+
+// extraGauge returns the gauge for the named metric, registering it
+// if this is the first time it has been seen.  nil is returned if the
+// gauge can't be created.
+func (m *Metrics) extraGauge(metric string) *prometheus.GaugeVec {
+	m.extraMutex.Lock()
+	defer m.extraMutex.Unlock()
+
+	if g, ok := m.extras[metric]; ok {
+		return g
+	}
+	if len(m.extras) >= maxExtraMetrics {
+		m.l.Warn("Too many extra metrics, ignoring", "metric", metric)
+		return nil
+	}
+
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: metric,
+		Help: "Value reported by the robot outside the fixed report.",
+	}, []string{"team"})
+	if err := m.r.Register(g); err != nil {
+		m.l.Warn("Could not register extra metric", "metric", metric, "error", err)
+		return nil
+	}
+	m.extras[metric] = g
+	return g
+}
+
+func (m *Metrics) deleteExtras(l prometheus.Labels) {
+	m.extraMutex.Lock()
+	defer m.extraMutex.Unlock()
+
+	for _, g := range m.extras {
+		g.Delete(l)
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Robots come and go as they are turned on and off, and a robot that
+// has stopped reporting must not leave its last values behind, since
+// those look like a healthy robot to anyone reading a dashboard.  All
+// metrics for a team are treated as one unit: they exist while the
+// robot is reporting, and all of them are removed once it has been
+// quiet for longer than the TTL.
+
+const (
+	defaultTTL = time.Second * 10
+)
+
+// markSeen records that a report was just received from the team.
+func (m *Metrics) markSeen(team string) {
+	m.robotMutex.Lock()
+	m.robots[team] = m.now()
+	m.robotMutex.Unlock()
+}
+
+// DeleteZombieRobot removes all metrics associated with a robot that
+// is no longer connected.
+func (m *Metrics) DeleteZombieRobot(team string) {
+	m.robotMutex.Lock()
+	delete(m.robots, team)
+	m.robotMutex.Unlock()
+
+	m.deleteTeam(team)
+}
+
+func (m *Metrics) deleteTeam(team string) {
+	l := prometheus.Labels{"team": team}
+	for _, d := range m.perTeam {
+		d.Delete(l)
+	}
+	m.deleteExtras(l)
+}
+
+// expire removes the metrics for every team that hasn't reported
+// within the TTL, and returns the teams that were removed.
+func (m *Metrics) expire() []string {
+	now := m.now()
+
+	m.robotMutex.Lock()
+	expired := []string{}
+	for team, seen := range m.robots {
+		if now.Sub(seen) > m.ttl {
+			expired = append(expired, team)
+			delete(m.robots, team)
+		}
+	}
+	m.robotMutex.Unlock()
+	sort.Strings(expired)
+
+	for _, team := range expired {
+		m.deleteTeam(team)
+		m.l.Debug("Robot is offline", "team", team)
+		if m.onOffline != nil {
+			m.onOffline(team)
+		}
+	}
+	return expired
+}
+
+// StartFlusher periodically removes the metrics for robots that have
+// stopped reporting so that they don't stick around as zombies.
+func (m *Metrics) StartFlusher() {
+	flushTicker := time.NewTicker(m.ttl / 2)
+
+	go func() {
+		for {
+			select {
+			case <-m.stopStatFlusher:
+				flushTicker.Stop()
+				return
+			case <-flushTicker.C:
+				m.expire()
+			}
+		}
+	}()
+}
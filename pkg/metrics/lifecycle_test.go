@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestMetrics(t *testing.T, offline func(string)) (*Metrics, *fakeClock) {
+	t.Helper()
+
+	clk := &fakeClock{t: time.Unix(1700000000, 0)}
+	m := New(WithTTL(time.Second*10), WithOfflineFunc(offline))
+	m.now = clk.now
+	return m, clk
+}
+
+func report(t *testing.T, m *Metrics, team string) {
+	t.Helper()
+
+	data := []byte(`{"RSSI":-50,"VBat":7,"VBatM":100000,"Extra":{"motor_current_1":1.5}}`)
+	if _, err := m.ParseReport(team, data); err != nil {
+		t.Fatalf("ParseReport: %v", err)
+	}
+}
+
+func seriesFor(t *testing.T, m *Metrics, team string) int {
+	t.Helper()
+
+	families, err := m.r.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	n := 0
+	for _, mf := range families {
+		for _, metric := range mf.GetMetric() {
+			for _, lp := range metric.GetLabel() {
+				if lp.GetName() == "team" && lp.GetValue() == team {
+					n++
+				}
+			}
+		}
+	}
+	return n
+}
+
+func TestExpireRemovesEveryTeam(t *testing.T) {
+	var mutex sync.Mutex
+	offline := []string{}
+	m, clk := newTestMetrics(t, func(team string) {
+		mutex.Lock()
+		offline = append(offline, team)
+		mutex.Unlock()
+	})
+
+	for _, team := range []string{"1", "2", "3"} {
+		report(t, m, team)
+	}
+	clk.advance(time.Second * 11)
+
+	expired := m.expire()
+	if len(expired) != 3 {
+		t.Fatalf("expected 3 teams to expire, got %v", expired)
+	}
+	if len(offline) != 3 {
+		t.Errorf("expected 3 offline events, got %v", offline)
+	}
+	for _, team := range []string{"1", "2", "3"} {
+		if n := seriesFor(t, m, team); n != 0 {
+			t.Errorf("team %s still has %d series", team, n)
+		}
+	}
+}
+
+func TestExpireKeepsFreshTeams(t *testing.T) {
+	m, clk := newTestMetrics(t, nil)
+
+	report(t, m, "1")
+	clk.advance(time.Second * 8)
+	report(t, m, "2")
+	clk.advance(time.Second * 3)
+
+	expired := m.expire()
+	if len(expired) != 1 || expired[0] != "1" {
+		t.Fatalf("expected only team 1 to expire, got %v", expired)
+	}
+	if n := seriesFor(t, m, "2"); n == 0 {
+		t.Error("team 2 should still have metrics")
+	}
+}
+
+func TestExpireIncludesLastInteraction(t *testing.T) {
+	m, clk := newTestMetrics(t, nil)
+
+	report(t, m, "1")
+	if v := testutil.ToFloat64(m.robotLastInteraction.WithLabelValues("1")); v != float64(clk.t.Unix()) {
+		t.Fatalf("last interaction is %v, expected %v", v, clk.t.Unix())
+	}
+
+	clk.advance(time.Second * 11)
+	m.expire()
+	if n := testutil.CollectAndCount(m.robotLastInteraction); n != 0 {
+		t.Errorf("last interaction still has %d series", n)
+	}
+}
+
+func TestOfflineIsATransition(t *testing.T) {
+	events := 0
+	m, clk := newTestMetrics(t, func(string) { events++ })
+
+	report(t, m, "1")
+	clk.advance(time.Second * 11)
+	m.expire()
+	m.expire()
+	if events != 1 {
+		t.Fatalf("expected 1 offline event, got %d", events)
+	}
+
+	report(t, m, "1")
+	clk.advance(time.Second * 11)
+	m.expire()
+	if events != 2 {
+		t.Errorf("expected a second offline event after reconnecting, got %d", events)
+	}
+}
+
+func TestNonPositiveTTLIsIgnored(t *testing.T) {
+	for _, ttl := range []time.Duration{0, -time.Second} {
+		m := New(WithTTL(ttl))
+		if m.ttl != defaultTTL {
+			t.Errorf("TTL of %v gave %v, expected the default", ttl, m.ttl)
+		}
+	}
+}
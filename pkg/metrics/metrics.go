@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"sync"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
@@ -19,7 +18,11 @@ func New(opts ...Option) *Metrics {
 		r:               prometheus.NewRegistry(),
 		broker:          "mqtt://127.0.0.1:1883",
 		stopStatFlusher: make(chan (struct{})),
-		lastSeen:        &sync.Map{},
+		ttl:             defaultTTL,
+		now:             time.Now,
+		robots:          make(map[string]time.Time),
+		extraAllow:      DefaultExtraAllowList,
+		extras:          make(map[string]*prometheus.GaugeVec),
 
 		robotRSSI: prometheus.NewGaugeVec(prometheus.GaugeOpts{
 			Namespace: "gizmo",
@@ -125,23 +128,46 @@ func New(opts ...Option) *Metrics {
 			Name:      "last_interaction",
 			Help:      "Timestamp of the last mqtt metrics push",
 		}, []string{"team"}),
+
+		robotControlRTT: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gizmo",
+			Subsystem: "robot",
+			Name:      "control_rtt_seconds",
+			Help:      "Round trip time of control frames as measured by the driver's station.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+		}, []string{"team"}),
+
+		robotControlLoss: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gizmo",
+			Subsystem: "robot",
+			Name:      "control_loss_ratio",
+			Help:      "Fraction of control frames lost between status reports.",
+			Buckets:   []float64{0, 0.01, 0.02, 0.05, 0.1, 0.25, 0.5, 1},
+		}, []string{"team"}),
 	}
 
-	x.r.MustRegister(x.robotRSSI)
-	x.r.MustRegister(x.robotWifiReconnects)
-	x.r.MustRegister(x.robotVBat)
-	x.r.MustRegister(x.robotPowerBoard)
-	x.r.MustRegister(x.robotPowerPico)
-	x.r.MustRegister(x.robotPowerGPIO)
-	x.r.MustRegister(x.robotPowerServo)
-	x.r.MustRegister(x.robotPowerBusA)
-	x.r.MustRegister(x.robotPowerBusB)
-	x.r.MustRegister(x.robotPowerPixels)
-	x.r.MustRegister(x.robotWatchdogOK)
-	x.r.MustRegister(x.robotWatchdogLifetime)
-	x.r.MustRegister(x.robotControlFrames)
-	x.r.MustRegister(x.robotControlFrameAge)
-	x.r.MustRegister(x.robotLastInteraction)
+	x.perTeam = []teamDeleter{
+		x.robotRSSI,
+		x.robotWifiReconnects,
+		x.robotVBat,
+		x.robotPowerBoard,
+		x.robotPowerPico,
+		x.robotPowerGPIO,
+		x.robotPowerServo,
+		x.robotPowerBusA,
+		x.robotPowerBusB,
+		x.robotPowerPixels,
+		x.robotWatchdogOK,
+		x.robotWatchdogLifetime,
+		x.robotControlFrames,
+		x.robotControlFrameAge,
+		x.robotLastInteraction,
+		x.robotControlRTT,
+		x.robotControlLoss,
+	}
+	for _, c := range x.perTeam {
+		x.r.MustRegister(c.(prometheus.Collector))
+	}
 
 	x.s = &http.Server{}
 
@@ -172,69 +198,24 @@ func (m *Metrics) Registry() *prometheus.Registry {
 	return m.r
 }
 
-// DeleteZombieRobot removes metrics associated with a zombie robot
-// that is no longer connected.
-func (m *Metrics) DeleteZombieRobot(team string) {
-	l := prometheus.Labels{"team": team}
-
-	m.robotRSSI.Delete(l)
-	m.robotWifiReconnects.Delete(l)
-	m.robotVBat.Delete(l)
-	m.robotPowerBoard.Delete(l)
-	m.robotPowerPico.Delete(l)
-	m.robotPowerGPIO.Delete(l)
-	m.robotPowerServo.Delete(l)
-	m.robotPowerBusA.Delete(l)
-	m.robotPowerBusB.Delete(l)
-	m.robotPowerPixels.Delete(l)
-	m.robotWatchdogOK.Delete(l)
-	m.robotWatchdogLifetime.Delete(l)
-	m.robotControlFrameAge.Delete(l)
-	m.robotControlFrames.Delete(l)
-}
-
-// StartFlusher clears the stats for robots every 10 seconds
-// ensuring that robots don't stick around as zombies in the system if
-// they've disconnected.
-func (m *Metrics) StartFlusher() {
-	flushTicker := time.NewTicker(time.Second * 10)
-
-	go func() {
-		for {
-			select {
-			case <-m.stopStatFlusher:
-				flushTicker.Stop()
-				return
-			case <-flushTicker.C:
-				now := time.Now()
-				m.lastSeen.Range(func(team, seen any) bool {
-					if now.Sub(seen.(time.Time)) > time.Second*10 {
-						m.DeleteZombieRobot(team.(string))
-					}
-					return false
-				})
-			}
-		}
-	}()
-}
-
 // Shutdown signals the flusher that we wish to cease operations.
 func (m *Metrics) Shutdown() {
 	m.stopStatFlusher <- struct{}{}
 }
 
-// ParseReport directly parses a report from a buffer.
-func (m *Metrics) ParseReport(teamNum string, data []byte) error {
-	var stats report
+// ParseReport directly parses a report from a buffer.  The parsed
+// report is returned so that it can be passed on elsewhere.
+func (m *Metrics) ParseReport(teamNum string, data []byte) (Report, error) {
+	var stats Report
 	if err := json.Unmarshal(data, &stats); err != nil {
 		m.l.Warn("Bad stats report", "team", teamNum, "error", err)
-		return err
+		return Report{}, err
+	}
+	if stats.Version > ReportVersion {
+		m.l.Trace("Report is newer than understood", "team", teamNum, "version", stats.Version)
 	}
 
-	// This uses the same conversion that's used on the Gizmo to
-	// drive the battery status LED, which is why it has to have
-	// access to the values from the Gizmo itself.
-	voltage := (float64(stats.VBatM)/100000)*float64(stats.VBat) + (float64(stats.VBatM) / 100000)
+	voltage := stats.Voltage()
 
 	m.robotRSSI.With(prometheus.Labels{"team": teamNum}).Set(float64(stats.RSSI))
 	m.robotWifiReconnects.With(prometheus.Labels{"team": teamNum}).Set(float64(stats.WifiReconnects))
@@ -251,10 +232,30 @@ func (m *Metrics) ParseReport(teamNum string, data []byte) error {
 	m.robotPowerBusB.With(prometheus.Labels{"team": teamNum}).Set(fCast(stats.PwrMainB))
 	m.robotPowerPixels.With(prometheus.Labels{"team": teamNum}).Set(fCast(stats.PwrPixels))
 	m.robotWatchdogOK.With(prometheus.Labels{"team": teamNum}).Set(fCast(stats.WatchdogOK))
+	m.observeExtras(teamNum, stats)
+
+	m.robotLastInteraction.With(prometheus.Labels{"team": teamNum}).Set(float64(m.now().Unix()))
+	m.markSeen(teamNum)
+	return stats, nil
+}
+
+// ObserveControlRTT records the round trip time of a control frame.
+func (m *Metrics) ObserveControlRTT(teamNum string, rtt time.Duration) {
+	m.robotControlRTT.With(prometheus.Labels{"team": teamNum}).Observe(rtt.Seconds())
+}
+
+// ObserveControlLoss records the fraction of control frames that were
+// lost over a reporting interval.
+func (m *Metrics) ObserveControlLoss(teamNum string, loss float64) {
+	m.robotControlLoss.With(prometheus.Labels{"team": teamNum}).Observe(loss)
+}
 
-	m.robotLastInteraction.With(prometheus.Labels{"team": teamNum}).SetToCurrentTime()
-	m.lastSeen.Store(teamNum, time.Now())
-	return nil
+// Voltage converts the packed battery reading into volts.  This uses
+// the same conversion that's used on the Gizmo to drive the battery
+// status LED, which is why it has to have access to the values from
+// the Gizmo itself.
+func (r Report) Voltage() float64 {
+	return (float64(r.VBatM)/100000)*float64(r.VBat) + (float64(r.VBatM) / 100000)
 }
 
 func fCast(b bool) float64 {
@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/hashicorp/go-hclog"
 )
 
@@ -19,3 +21,32 @@ func WithBroker(b string) Option {
 		m.broker = b
 	}
 }
+
+// WithExtraAllowList sets the patterns that extra values in a report
+// must match to be exported.
+func WithExtraAllowList(patterns []string) Option {
+	return func(m *Metrics) {
+		m.extraAllow = patterns
+	}
+}
+
+// WithTTL sets how long a robot may go without reporting before its
+// metrics are removed.  A TTL that isn't positive is ignored and the
+// default is kept.
+func WithTTL(d time.Duration) Option {
+	return func(m *Metrics) {
+		if d <= 0 {
+			return
+		}
+		m.ttl = d
+	}
+}
+
+// WithOfflineFunc sets a function that is called with the team number
+// whenever a robot's metrics are removed because it stopped
+// reporting.
+func WithOfflineFunc(f func(string)) Option {
+	return func(m *Metrics) {
+		m.onOffline = f
+	}
+}
@@ -3,6 +3,7 @@ package metrics
 import (
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/prometheus/client_golang/prometheus"
@@ -31,12 +32,42 @@ type Metrics struct {
 	robotControlFrameAge  *prometheus.GaugeVec
 	robotControlFrames    *prometheus.GaugeVec
 	robotLastInteraction  *prometheus.GaugeVec
+	robotControlRTT       *prometheus.HistogramVec
+	robotControlLoss      *prometheus.HistogramVec
+
+	extraAllow []string
+	extraMutex sync.Mutex
+	extras     map[string]*prometheus.GaugeVec
+
+	// perTeam is every metric that is labeled by team, which
+	// allows them to all be removed together when a robot goes
+	// offline.
+	perTeam []teamDeleter
+
+	ttl       time.Duration
+	now       func() time.Time
+	onOffline func(string)
 
 	stopStatFlusher chan struct{}
-	lastSeen        *sync.Map
+	robotMutex      sync.Mutex
+	robots          map[string]time.Time
 }
 
-type report struct {
+// teamDeleter is satisfied by all the metric vectors that are labeled
+// by team.
+type teamDeleter interface {
+	Delete(prometheus.Labels) bool
+}
+
+// Report is the status report that a Gizmo sends to its driver's
+// station.  It is exported so that the driver's station can forward
+// it on to the FMS.
+type Report struct {
+	// Version is the schema version of the report.  Firmware that
+	// predates versioning doesn't send this, in which case it is
+	// zero.
+	Version int
+
 	ControlFrameAge       int32
 	ControlFramesReceived int32
 	VBat                  int32
@@ -53,6 +84,41 @@ type report struct {
 	PwrMainA              bool
 	PwrMainB              bool
 	PwrPixels             bool
+
+	// LastSeq is the sequence number of the most recent control
+	// frame the Gizmo has seen.  Older firmware doesn't send
+	// this, in which case it is zero.
+	LastSeq uint32
+
+	// Extra carries named numeric and boolean values that aren't
+	// part of the fixed report.  This lets newer firmware report
+	// things without needing a coordinated driver's station
+	// release.
+	Extra map[string]interface{}
+}
+
+// LinkStats describes how well control frames are being delivered
+// from the driver's station to the Gizmo.
+type LinkStats struct {
+	// Loss is the fraction of control frames that were sent but
+	// never received over the last reporting interval.
+	Loss float64
+
+	// RTT is the round trip time of a control frame in seconds.
+	RTT float64
+}
+
+// SentHeader carries the driver's station's clock at the time a
+// batch of samples is sent, so that the FMS can correct the sample
+// times for any difference between the two clocks.
+const SentHeader = "X-Gizmo-Sent"
+
+// Sample is a report along with the time that it was received.
+// Samples are batched up by the driver's station and sent to the FMS.
+type Sample struct {
+	Time   time.Time
+	Report Report
+	Link   LinkStats
 }
 
 // Option provides a configuration framework to setup the metrics
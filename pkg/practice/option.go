@@ -0,0 +1,19 @@
+package practice
+
+import (
+	"github.com/hashicorp/go-hclog"
+)
+
+// WithLogger configures the logger for the store.
+func WithLogger(l hclog.Logger) Option {
+	return func(s *Store) {
+		s.l = l.Named("practice")
+	}
+}
+
+// WithFile sets the file that the bookings are saved to.
+func WithFile(p string) Option {
+	return func(s *Store) {
+		s.path = p
+	}
+}
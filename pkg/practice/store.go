@@ -0,0 +1,230 @@
+// Package practice keeps the bookings for practice fields.  Teams
+// reserve a quad for a slot of time, and the FMS maps them there for
+// the length of the slot.
+package practice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/gizmo-platform/gizmo/pkg/util"
+)
+
+// bookingRetention is how long a booking is kept after it ends, so
+// that the board can still show the slots from earlier in the day.
+const bookingRetention = time.Hour * 24
+
+var (
+	// ErrNoSuchBooking is returned when a booking is requested
+	// that doesn't exist.
+	ErrNoSuchBooking = errors.New("no booking with that ID exists")
+
+	// ErrBadSlot is returned when a booking doesn't end after it
+	// starts, or has already ended.
+	ErrBadSlot = errors.New("bookings must end after they start and after the current time")
+)
+
+// New returns a store configured with the given options.
+func New(opts ...Option) *Store {
+	s := new(Store)
+	s.l = hclog.NewNullLogger()
+	s.path = "practice.json"
+	s.nextID = 1
+
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Load reads the bookings back from disk.  It is not an error for
+// there to be no bookings yet.
+func (s *Store) Load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bookings := []Booking{}
+	if err := json.NewDecoder(f).Decode(&bookings); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.bookings = bookings
+	for _, b := range bookings {
+		if b.ID >= s.nextID {
+			s.nextID = b.ID + 1
+		}
+	}
+	s.mutex.Unlock()
+	s.l.Info("Bookings loaded", "count", len(bookings))
+	return nil
+}
+
+// List returns all the bookings in the order that they start.
+func (s *Store) List() []Booking {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	out := append([]Booking{}, s.bookings...)
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Start.Equal(out[j].Start) {
+			return out[i].Start.Before(out[j].Start)
+		}
+		return out[i].Quad < out[j].Quad
+	})
+	return out
+}
+
+// Book adds a booking and returns it with its ID filled in.  A quad
+// can only be booked by one team at a time, and a team can only be
+// booked on one quad at a time.
+func (s *Store) Book(b Booking) (Booking, error) {
+	if !b.End.After(b.Start) || !b.End.After(time.Now()) {
+		return Booking{}, ErrBadSlot
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, o := range s.bookings {
+		if o.Ended || !b.overlaps(o) {
+			continue
+		}
+		if o.Quad == b.Quad {
+			return Booking{}, fmt.Errorf("%s is already booked by team %d from %s to %s", o.Quad, o.Team, o.Start.Format("15:04"), o.End.Format("15:04"))
+		}
+		return Booking{}, fmt.Errorf("team %d is already booked on %s from %s to %s", o.Team, o.Quad, o.Start.Format("15:04"), o.End.Format("15:04"))
+	}
+
+	b.ID = s.nextID
+	b.Started = false
+	b.Ended = false
+	bookings := append(s.prune(time.Now()), b)
+	if err := s.save(bookings); err != nil {
+		return Booking{}, err
+	}
+	s.bookings = bookings
+	s.nextID++
+	s.l.Info("Booking added", "id", b.ID, "team", b.Team, "quad", b.Quad, "start", b.Start, "end", b.End)
+	return b, nil
+}
+
+// Cancel removes a booking and returns what it was, so that the
+// caller can unmap the team if the booking was active.
+func (s *Store) Cancel(id int) (Booking, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	bookings := []Booking{}
+	found := Booking{}
+	for _, b := range s.bookings {
+		if b.ID == id {
+			found = b
+			continue
+		}
+		bookings = append(bookings, b)
+	}
+	if found.ID == 0 {
+		return Booking{}, ErrNoSuchBooking
+	}
+	if err := s.save(bookings); err != nil {
+		return Booking{}, err
+	}
+	s.bookings = bookings
+	s.l.Info("Booking cancelled", "id", id, "team", found.Team, "quad", found.Quad)
+	return found, nil
+}
+
+// Due returns the bookings that should be started and ended at the
+// given time.  A booking whose slot passed entirely while the FMS was
+// down is ended without ever being started.
+func (s *Store) Due(now time.Time) (start, end []Booking) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, b := range s.bookings {
+		switch {
+		case b.Ended:
+		case !now.Before(b.End):
+			end = append(end, b)
+		case !b.Started && b.Skipped == "" && !now.Before(b.Start):
+			start = append(start, b)
+		}
+	}
+	return start, end
+}
+
+// Mark records that bookings have been started, skipped, or ended.
+// Skipped bookings carry the reason they were skipped.
+func (s *Store) Mark(started, skipped, ended []Booking) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	bookings := append([]Booking{}, s.bookings...)
+	for i := range bookings {
+		for _, b := range started {
+			if bookings[i].ID == b.ID {
+				bookings[i].Started = true
+			}
+		}
+		for _, b := range skipped {
+			if bookings[i].ID == b.ID {
+				bookings[i].Skipped = b.Skipped
+			}
+		}
+		for _, b := range ended {
+			if bookings[i].ID == b.ID {
+				bookings[i].Ended = true
+			}
+		}
+	}
+	if err := s.save(bookings); err != nil {
+		return err
+	}
+	s.bookings = bookings
+	return nil
+}
+
+// Active returns the team holding each quad that has an active
+// booking.
+func (s *Store) Active() map[string]int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	out := make(map[string]int)
+	for _, b := range s.bookings {
+		if b.Active() {
+			out[b.Quad] = b.Team
+		}
+	}
+	return out
+}
+
+// prune drops bookings that ended long enough ago that nobody needs
+// to see them anymore.
+func (s *Store) prune(now time.Time) []Booking {
+	out := []Booking{}
+	for _, b := range s.bookings {
+		if b.Ended && now.Sub(b.End) > bookingRetention {
+			continue
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func (s *Store) save(bookings []Booking) error {
+	return util.WriteJSONFile(s.path, bookings)
+}
@@ -0,0 +1,62 @@
+package practice
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Store keeps the practice field bookings so that they survive
+// restarts of the FMS.
+type Store struct {
+	l hclog.Logger
+
+	path string
+
+	mutex    sync.RWMutex
+	bookings []Booking
+	nextID   int
+}
+
+// Option configures the Store.
+type Option func(*Store)
+
+// Booking reserves a single quad for a team for a slot of time.
+type Booking struct {
+	ID    int
+	Team  int
+	Quad  string
+	Start time.Time
+	End   time.Time
+
+	// BookedBy is who made the booking, so that the field crew
+	// knows who to talk to if there's a problem with it.
+	BookedBy string
+
+	// Started and Ended are set once the team has been mapped to
+	// and unmapped from the quad, so that a restart of the FMS
+	// doesn't map them again.
+	Started bool
+	Ended   bool
+
+	// Skipped is why the team wasn't mapped when the slot
+	// started, if they couldn't be.  A skipped slot is never
+	// started, but still ends so that it drops off the board.
+	Skipped string
+}
+
+// Active returns true if the team has been mapped for the booking and
+// not yet unmapped.
+func (b Booking) Active() bool {
+	return b.Started && !b.Ended
+}
+
+// overlaps returns true if the two bookings are for the same quad or
+// the same team at the same time.
+func (b Booking) overlaps(o Booking) bool {
+	if b.Quad != o.Quad && b.Team != o.Team {
+		return false
+	}
+	return b.Start.Before(o.End) && o.Start.Before(b.End)
+}
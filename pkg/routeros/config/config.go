@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/vishvananda/netlink"
 
 	"github.com/gizmo-platform/gizmo/pkg/eventstream"
@@ -37,6 +38,12 @@ const (
 	NormalAddr = "100.64.0.1"
 )
 
+var (
+	// ErrNoNeighbor is returned when there is no LLDP neighbor on
+	// a port, which is normal for a quad with nothing plugged in.
+	ErrNoNeighbor = errors.New("not found")
+)
+
 // New initializes and returns a configurator
 func New(opts ...Option) *Configurator {
 	c := new(Configurator)
@@ -44,6 +51,19 @@ func New(opts ...Option) *Configurator {
 	c.routerAddr = NormalAddr
 	c.ctx = make(map[string]interface{})
 	c.es = eventstream.NewNullStreamer()
+	c.opDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "gizmo",
+		Subsystem: "fms_net",
+		Name:      "operation_duration_seconds",
+		Help:      "Time taken to perform operations against network hardware.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"operation"})
+	c.opFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "gizmo",
+		Subsystem: "fms_net",
+		Name:      "operation_failures_total",
+		Help:      "Operations against network hardware that returned an error.",
+	}, []string{"operation"})
 	c.cl = &http.Client{
 		Timeout: time.Second * 10,
 		Transport: &http.Transport{
@@ -124,7 +144,7 @@ func (c *Configurator) SyncTLM(tlm map[int]string) error {
 		if fMap[fNum] == nil {
 			fMap[fNum] = make(map[string]int)
 		}
-		fMap[fNum][c.quadToEther(parts[1])] = c.fc.Teams[team].VLAN
+		fMap[fNum][QuadToEther(parts[1])] = c.fc.Teams[team].VLAN
 	}
 
 	f, err := os.Create(filepath.Join(c.stateDir, "tlm.json"))
@@ -171,6 +191,10 @@ func (c *Configurator) Init() error {
 // Converge commands all network hardware to achieve the state
 // currently on disk.
 func (c *Configurator) Converge(refresh bool, target string) error {
+	return c.observe("converge", func() error { return c.converge(refresh, target) })
+}
+
+func (c *Configurator) converge(refresh bool, target string) error {
 	opts := []string{"apply", "-auto-approve", "-no-color"}
 	if !refresh {
 		opts = append(opts, "-refresh=false")
@@ -290,6 +314,10 @@ func (c *Configurator) ReprovisionCAP() error {
 
 // CycleRadio forces a provisioning cycle on the given band.
 func (c *Configurator) CycleRadio(band string) error {
+	return c.observe("cycle_radio", func() error { return c.cycleRadio(band) })
+}
+
+func (c *Configurator) cycleRadio(band string) error {
 	// This only needs to happen if the field radio is the one
 	// that is in use.  If its not, other mechanisms are in play.
 	if c.fc.RadioMode != "FIELD" {
@@ -473,7 +501,7 @@ func (c *Configurator) GetIDOnPort(field int, quad string) (int, error) {
 	}
 
 	for _, neighbor := range res {
-		if strings.Contains(neighbor.Interface, c.quadToEther(quad)) {
+		if strings.Contains(neighbor.Interface, QuadToEther(quad)) {
 			num, err := strconv.Atoi(strings.TrimPrefix(neighbor.Identity, "gizmoDS-"))
 			if err != nil {
 				return -1, err
@@ -481,7 +509,7 @@ func (c *Configurator) GetIDOnPort(field int, quad string) (int, error) {
 			return num, nil
 		}
 	}
-	return -1, errors.New("not found")
+	return -1, ErrNoNeighbor
 }
 
 func (c *Configurator) syncFMSConfig() error {
@@ -632,7 +660,9 @@ func (c *Configurator) configureWorkspace(ctx map[string]interface{}) error {
 	return nil
 }
 
-func (c *Configurator) quadToEther(quad string) string {
+// QuadToEther returns the name of the field port that a given quad
+// color is cabled to.
+func QuadToEther(quad string) string {
 	switch quad {
 	case "red":
 		return "ether2"
@@ -645,3 +675,15 @@ func (c *Configurator) quadToEther(quad string) string {
 	}
 	return ""
 }
+
+// observe runs the operation and records how long it took and whether
+// it failed.
+func (c *Configurator) observe(op string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	c.opDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	if err != nil {
+		c.opFailures.WithLabelValues(op).Inc()
+	}
+	return err
+}
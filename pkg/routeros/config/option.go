@@ -2,6 +2,7 @@ package config
 
 import (
 	"github.com/hashicorp/go-hclog"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/gizmo-platform/gizmo/pkg/config"
 )
@@ -33,3 +34,9 @@ func WithRouter(ip string) Option {
 func WithEventStreamer(es EventStreamer) Option {
 	return func(c *Configurator) { c.es = es }
 }
+
+// WithRegisterer registers the configurator's metrics so that the
+// time spent talking to network hardware can be observed.
+func WithRegisterer(r prometheus.Registerer) Option {
+	return func(c *Configurator) { r.MustRegister(c.opDuration, c.opFailures) }
+}
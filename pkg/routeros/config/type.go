@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	"github.com/hashicorp/go-hclog"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/gizmo-platform/gizmo/pkg/config"
 )
@@ -38,6 +39,9 @@ type Configurator struct {
 	routerAddr string
 
 	ctx map[string]interface{}
+
+	opDuration *prometheus.HistogramVec
+	opFailures *prometheus.CounterVec
 }
 
 type rosInterface struct {
@@ -0,0 +1,253 @@
+package schedule
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The generator builds the schedule one match at a time.  Each match
+// takes the teams that have played the fewest matches and have been
+// off the field for long enough, and then spreads them over the
+// fields so that teams meet as many different opponents as possible
+// and play from every quadrant.  Greedy choices early on can paint
+// the generator into a corner, so several schedules are generated
+// and the one with the fewest repeats is kept.
+
+const (
+	generateAttempts = 50
+
+	// Breaking the turnaround is far worse than meeting the same
+	// team twice, so it costs a lot more.
+	costTurnaround = 1000
+)
+
+var (
+	// ErrNoTeams is returned when there is nobody to schedule.
+	ErrNoTeams = errors.New("there are no teams to schedule")
+
+	// ErrNoQuads is returned when there are no fields to schedule
+	// matches on.
+	ErrNoQuads = errors.New("there are no fields to schedule matches on")
+
+	// ErrMatchesPerTeam is returned when teams aren't scheduled to
+	// play any matches.
+	ErrMatchesPerTeam = errors.New("teams must play at least one match")
+
+	// ErrTurnaround is returned when there aren't enough teams to
+	// keep every team off the field for the turnaround.
+	ErrTurnaround = errors.New("there are not enough teams for that turnaround")
+)
+
+type generator struct {
+	p   Params
+	rng *rand.Rand
+
+	size    int
+	matches int
+	fields  []string
+	quads   map[string][]string
+
+	played map[int]int
+	last   map[int]int
+	met    map[[2]int]int
+	seat   map[int]map[string]int
+	cost   int
+}
+
+// Generate builds a balanced qualification schedule.  Every team
+// plays the same number of matches, so the last few matches may have
+// empty quads if the number of teams doesn't divide evenly.
+func Generate(p Params) (Schedule, error) {
+	switch {
+	case len(p.Teams) == 0:
+		return Schedule{}, ErrNoTeams
+	case len(p.Quads) == 0:
+		return Schedule{}, ErrNoQuads
+	case p.MatchesPerTeam < 1:
+		return Schedule{}, ErrMatchesPerTeam
+	case p.Turnaround < 0:
+		p.Turnaround = 0
+	}
+
+	size := len(p.Quads)
+	if len(p.Teams) < size {
+		size = len(p.Teams)
+	}
+	if len(p.Teams) < size*(p.Turnaround+1) {
+		return Schedule{}, ErrTurnaround
+	}
+
+	rng := rand.New(rand.NewSource(p.Seed))
+	var best []Match
+	bestCost := 0
+	for i := 0; i < generateAttempts; i++ {
+		g := newGenerator(p, size, rng)
+		matches := g.run()
+		if best == nil || g.cost < bestCost {
+			best = matches
+			bestCost = g.cost
+		}
+	}
+
+	return Schedule{
+		Generated: time.Now(),
+		Params:    p,
+		Matches:   best,
+	}, nil
+}
+
+func newGenerator(p Params, size int, rng *rand.Rand) *generator {
+	g := &generator{
+		p:       p,
+		rng:     rng,
+		size:    size,
+		matches: (len(p.Teams)*p.MatchesPerTeam + size - 1) / size,
+		quads:   make(map[string][]string),
+		played:  make(map[int]int),
+		last:    make(map[int]int),
+		met:     make(map[[2]int]int),
+		seat:    make(map[int]map[string]int),
+	}
+
+	for _, quad := range p.Quads {
+		field := strings.TrimSuffix(quad, ":"+color(quad))
+		if _, ok := g.quads[field]; !ok {
+			g.fields = append(g.fields, field)
+		}
+		g.quads[field] = append(g.quads[field], quad)
+	}
+
+	for _, team := range p.Teams {
+		g.last[team] = -p.Turnaround - 1
+		g.seat[team] = make(map[string]int)
+	}
+	return g
+}
+
+func (g *generator) run() []Match {
+	out := []Match{}
+	for n := 1; ; n++ {
+		teams := g.pick(n)
+		if len(teams) == 0 {
+			return out
+		}
+		out = append(out, Match{Number: n, Mapping: g.place(n, teams)})
+	}
+}
+
+// pick chooses the teams for a match.  Teams that are rested enough
+// come first, and then teams that have played the least.  Teams are
+// shuffled first so that ties are broken differently every attempt.
+// If the matches can't all be full, the empty quads are spread over
+// the last few matches rather than leaving one nearly empty match.
+func (g *generator) pick(n int) []int {
+	candidates := []int{}
+	remaining := 0
+	for _, team := range g.p.Teams {
+		if g.played[team] < g.p.MatchesPerTeam {
+			candidates = append(candidates, team)
+			remaining += g.p.MatchesPerTeam - g.played[team]
+		}
+	}
+
+	size := g.size
+	if left := g.matches - n + 1; left > 0 {
+		size = (remaining + left - 1) / left
+	}
+	g.rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ri, rj := g.rested(candidates[i], n), g.rested(candidates[j], n)
+		if ri != rj {
+			return ri
+		}
+		return g.played[candidates[i]] < g.played[candidates[j]]
+	})
+
+	if len(candidates) > size {
+		candidates = candidates[:size]
+	}
+	for _, team := range candidates {
+		if !g.rested(team, n) {
+			g.cost += costTurnaround
+		}
+	}
+	return candidates
+}
+
+func (g *generator) rested(team, n int) bool {
+	return n-g.last[team] > g.p.Turnaround
+}
+
+// place assigns the teams in a match to quads.  Each team goes to the
+// field where it has met the other teams the fewest times, preferring
+// emptier fields so that partial matches are spread out, and then to
+// the quadrant it has played from the least.
+func (g *generator) place(n int, teams []int) map[int]string {
+	onField := make(map[string][]int)
+	mapping := make(map[int]string, len(teams))
+
+	for _, team := range teams {
+		bestField := ""
+		bestMet := 0
+		for _, field := range g.fields {
+			if len(onField[field]) == len(g.quads[field]) {
+				continue
+			}
+			met := 0
+			for _, other := range onField[field] {
+				met += g.met[pair(team, other)]
+			}
+			if bestField == "" || met < bestMet || (met == bestMet && len(onField[field]) < len(onField[bestField])) {
+				bestField = field
+				bestMet = met
+			}
+		}
+		onField[bestField] = append(onField[bestField], team)
+	}
+
+	for _, field := range g.fields {
+		taken := make(map[string]bool)
+		for i, team := range onField[field] {
+			bestQuad := ""
+			for _, quad := range g.quads[field] {
+				if taken[quad] {
+					continue
+				}
+				if bestQuad == "" || g.seat[team][color(quad)] < g.seat[team][color(bestQuad)] {
+					bestQuad = quad
+				}
+			}
+			taken[bestQuad] = true
+			mapping[team] = bestQuad
+
+			g.cost += g.seat[team][color(bestQuad)]
+			g.seat[team][color(bestQuad)]++
+			for _, other := range onField[field][:i] {
+				g.cost += g.met[pair(team, other)]
+				g.met[pair(team, other)]++
+			}
+			g.played[team]++
+			g.last[team] = n
+		}
+	}
+	return mapping
+}
+
+// color returns the quadrant part of a quad, so that playing from red
+// on two different fields counts as playing from red twice.
+func color(quad string) string {
+	parts := strings.SplitN(quad, ":", 2)
+	return parts[len(parts)-1]
+}
+
+func pair(a, b int) [2]int {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]int{a, b}
+}
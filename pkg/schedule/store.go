@@ -0,0 +1,122 @@
+// Package schedule generates and stores the qualification schedule.
+package schedule
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/gizmo-platform/gizmo/pkg/util"
+)
+
+var (
+	// ErrNoSuchMatch is returned when a match is requested that
+	// isn't in the schedule.
+	ErrNoSuchMatch = errors.New("no match with that number is scheduled")
+)
+
+// New returns a store configured with the given options.
+func New(opts ...Option) *Store {
+	s := new(Store)
+	s.l = hclog.NewNullLogger()
+	s.path = "schedule.json"
+
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+// Load reads the schedule back from disk.  It is not an error for
+// there to be no schedule yet.
+func (s *Store) Load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sch := Schedule{}
+	if err := json.NewDecoder(f).Decode(&sch); err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	s.current = sch
+	s.mutex.Unlock()
+	s.l.Info("Schedule loaded", "matches", len(sch.Matches))
+	return nil
+}
+
+// Get returns the current schedule.
+func (s *Store) Get() Schedule {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.current
+}
+
+// Replace swaps the current schedule for a new one and saves it.
+func (s *Store) Replace(sch Schedule) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := util.WriteJSONFile(s.path, sch); err != nil {
+		return err
+	}
+	s.current = sch
+	s.l.Info("Schedule replaced", "matches", len(sch.Matches))
+	return nil
+}
+
+// Match returns the match with the given number.
+func (s *Store) Match(number int) (Match, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, m := range s.current.Matches {
+		if m.Number == number {
+			return m, nil
+		}
+	}
+	return Match{}, ErrNoSuchMatch
+}
+
+// WriteCSV writes the schedule with one row per match and one column
+// per quad, in the same order as the quads it was generated for.
+// Empty quads are left blank.
+func (sch Schedule) WriteCSV(w io.Writer) error {
+	quads := sch.Params.Quads
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"match"}, quads...)); err != nil {
+		return err
+	}
+	for _, m := range sch.Matches {
+		byQuad := make(map[string]int, len(m.Mapping))
+		for team, quad := range m.Mapping {
+			byQuad[quad] = team
+		}
+
+		row := []string{strconv.Itoa(m.Number)}
+		for _, quad := range quads {
+			cell := ""
+			if team, ok := byQuad[quad]; ok {
+				cell = strconv.Itoa(team)
+			}
+			row = append(row, cell)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,56 @@
+package schedule
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Store keeps the qualification schedule so that it survives
+// restarts of the FMS.
+type Store struct {
+	l hclog.Logger
+
+	path string
+
+	mutex   sync.RWMutex
+	current Schedule
+}
+
+// Option configures the Store.
+type Option func(*Store)
+
+// Schedule is the list of qualification matches in the order they
+// are to be played, along with what it was generated from.
+type Schedule struct {
+	Generated time.Time
+	Params    Params
+	Matches   []Match
+}
+
+// Match is a single match in the schedule.  The mapping is from team
+// number to quad, which is the same form that the TLM uses, so a
+// match can be staged directly.  Quads with nobody in them are left
+// out of the mapping.
+type Match struct {
+	Number  int
+	Mapping map[int]string
+}
+
+// Params control how a schedule is generated.
+type Params struct {
+	Teams []int
+	Quads []string
+
+	// MatchesPerTeam is how many matches every team plays.
+	MatchesPerTeam int
+
+	// Turnaround is the minimum number of matches that must be
+	// played between two matches for the same team, so that
+	// they have time to get back to the pits and fix things.
+	Turnaround int
+
+	// Seed makes it possible to generate the same schedule again.
+	Seed int64
+}
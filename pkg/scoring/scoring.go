@@ -0,0 +1,154 @@
+// Package scoring totals the scores that are entered for each match
+// and ranks teams over the qualification matches.  It is intended for
+// small events that don't have a scoring system of their own.
+package scoring
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gizmo-platform/gizmo/pkg/config"
+	"github.com/gizmo-platform/gizmo/pkg/match"
+)
+
+// Sheet is the score for a single match.  Scores are entered for each
+// quadrant that had a team mapped to it, so the teams are always the
+// ones that were actually on the field.
+type Sheet struct {
+	// Record is the ID of the match record that the sheet scores.
+	// Number starts out as the match number from the record, but
+	// may be changed by the scorekeeper.
+	Record int
+	Number int
+
+	Entries map[string]*Entry
+	Updated time.Time
+}
+
+// Entry is the score for one quadrant.  Counts are stored rather than
+// points so that changing what an element is worth changes the score
+// of matches that have already been played.
+type Entry struct {
+	Team   int
+	Counts map[string]int
+
+	// Score and Won are filled in by Tally.
+	Score int
+	Won   bool
+}
+
+// Ranking is a team's standing over the qualification matches.
+type Ranking struct {
+	Rank    int
+	Team    int
+	Played  int
+	Wins    int
+	Total   int
+	High    int
+	Average float64
+}
+
+// NewSheet returns an empty sheet for the teams in the record.
+func NewSheet(rec match.Record) *Sheet {
+	s := &Sheet{
+		Record:  rec.ID,
+		Number:  rec.Number,
+		Entries: make(map[string]*Entry, len(rec.Mapping)),
+	}
+	for team, quad := range rec.Mapping {
+		s.Entries[quad] = &Entry{Team: team, Counts: make(map[string]int)}
+	}
+	return s
+}
+
+// Field returns the field part of a quad, for example "field1" for
+// "field1:red".
+func Field(quad string) string {
+	return strings.SplitN(quad, ":", 2)[0]
+}
+
+// Tally works out the score for each entry from the counts, and which
+// entry won on each field.  Only an outright high score on a field
+// with more than one team is a win.
+func (s *Sheet) Tally(elements []*config.ScoringElement) {
+	best := make(map[string]*Entry)
+	tied := make(map[string]bool)
+	teams := make(map[string]int)
+
+	for quad, e := range s.Entries {
+		e.Score = 0
+		e.Won = false
+		for _, el := range elements {
+			e.Score += e.Counts[el.Key] * el.Points
+		}
+
+		field := Field(quad)
+		teams[field]++
+		switch b := best[field]; {
+		case b == nil || e.Score > b.Score:
+			best[field] = e
+			tied[field] = false
+		case e.Score == b.Score:
+			tied[field] = true
+		}
+	}
+
+	for field, e := range best {
+		e.Won = teams[field] > 1 && !tied[field]
+	}
+}
+
+// Rank ranks teams by their average score, then by their high score.
+// Matches without a number weren't scheduled and so aren't
+// qualification matches, and if a match was replayed only the most
+// recent record with its number counts.
+func Rank(sheets []*Sheet) []Ranking {
+	latest := make(map[int]*Sheet)
+	for _, s := range sheets {
+		if s.Number == 0 {
+			continue
+		}
+		if prev, ok := latest[s.Number]; !ok || s.Record > prev.Record {
+			latest[s.Number] = s
+		}
+	}
+
+	byTeam := make(map[int]*Ranking)
+	for _, s := range latest {
+		for _, e := range s.Entries {
+			r, ok := byTeam[e.Team]
+			if !ok {
+				r = &Ranking{Team: e.Team, High: e.Score}
+				byTeam[e.Team] = r
+			}
+			r.Played++
+			r.Total += e.Score
+			if e.Score > r.High {
+				r.High = e.Score
+			}
+			if e.Won {
+				r.Wins++
+			}
+		}
+	}
+
+	out := make([]Ranking, 0, len(byTeam))
+	for _, r := range byTeam {
+		r.Average = float64(r.Total) / float64(r.Played)
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Average != out[j].Average {
+			return out[i].Average > out[j].Average
+		}
+		if out[i].High != out[j].High {
+			return out[i].High > out[j].High
+		}
+		return out[i].Team < out[j].Team
+	})
+	for i := range out {
+		out[i].Rank = i + 1
+	}
+	return out
+}
@@ -18,8 +18,9 @@ type DogHandFunc func()
 type Dog struct {
 	l hclog.Logger
 
-	name string
-	t    *time.Timer
+	name    string
+	t       *time.Timer
+	lastFed time.Time
 
 	biteFunc     DogHandFunc
 	foodDuration time.Duration
@@ -37,6 +38,7 @@ func New(opts ...Option) *Dog {
 	for _, o := range opts {
 		o(d)
 	}
+	d.lastFed = time.Now()
 	d.t = time.AfterFunc(d.foodDuration, d.Bite)
 	return d
 }
@@ -52,9 +54,22 @@ func (d *Dog) Bite() {
 // Feed convinces the dog not to bite for the values specified during
 // initialization, by default another 10 seconds.
 func (d *Dog) Feed() {
+	d.lastFed = time.Now()
 	d.t.Reset(d.foodDuration)
 }
 
+// Margin returns how much longer the dog would have waited before
+// biting.  Checking this right before feeding shows how close things
+// came to going wrong.
+func (d *Dog) Margin() time.Duration {
+	return d.foodDuration - time.Since(d.lastFed)
+}
+
+// Name returns the name of the dog.
+func (d *Dog) Name() string {
+	return d.name
+}
+
 // WithHandFunction sets up the hand that the dog will bite.  Not
 // setting this kind of defeats the point of having a watchdog.
 func WithHandFunction(f DogHandFunc) Option { return func(d *Dog) { d.biteFunc = f } }